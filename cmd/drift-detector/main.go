@@ -27,10 +27,19 @@ func main() {
 
 	// Check for command-line arguments
 	if len(os.Args) < 2 {
-		fmt.Println("Usage: go run main.go [up|down|detect] [instance-id (for down)] [tfstate-file (for detect)]")
+		fmt.Println("Usage: go run main.go [up|down|detect|diff-state] [instance-id (for down)] [-input=tfstate|show-state|show-plan|hcl] [-hcl-state=file] [-address-prefix=module.app] [-ignore-tags=aws:*] [-suppressions=file] [-severity-policy=file] [-fail-on=low|medium|high|critical] [-discover=dir] [-s3-endpoint=url] [-http-lock] [-lock-mode=warn|wait|abort] [-lock-timeout=5m] [tfstate-files or globs (for detect)] [old-state new-state (for diff-state)]")
 		fmt.Println("Example: go run main.go up")
 		fmt.Println("Example: go run main.go down i-1234567890abcdef0")
 		fmt.Println("Example: go run main.go detect terraform.tfstate")
+		fmt.Println("Example: go run main.go detect -input=show-plan plan.json")
+		fmt.Println("Example: go run main.go detect -input=hcl infrastructure/app")
+		fmt.Println("Example: go run main.go detect 'states/*.tfstate' network.tfstate")
+		fmt.Println("Example: go run main.go detect -discover=infrastructure/")
+		fmt.Println("Example: go run main.go detect -fail-on=high terraform.tfstate")
+		fmt.Println("Example: go run main.go detect -lock-mode=wait -lock-timeout=10m terraform.tfstate")
+		fmt.Println("Example: go run main.go diff-state yesterday.tfstate terraform.tfstate")
+		fmt.Println("Example: go run main.go detect 's3://my-states/app/terraform.tfstate?workspace=staging'")
+		fmt.Println("Example: TF_HTTP_USERNAME=user TF_HTTP_PASSWORD=token go run main.go detect -http-lock https://gitlab.example.com/api/v4/projects/1/terraform/state/prod")
 		os.Exit(1)
 	}
 
@@ -2,7 +2,12 @@ package commands
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"math/rand/v2"
@@ -10,6 +15,7 @@ import (
 	"github.com/cstudio7/drift-detector/internal/domain/entities"
 	awsClient "github.com/cstudio7/drift-detector/internal/interfaces/aws"
 	"github.com/cstudio7/drift-detector/internal/interfaces/logger"
+	"github.com/cstudio7/drift-detector/internal/interfaces/terraform"
 	"github.com/cstudio7/drift-detector/internal/usecases"
 	awsSDK "github.com/cstudio7/drift-detector/pkg/aws"
 )
@@ -157,37 +163,254 @@ func (c *DriftCommand) Run(args []string) error {
 		c.logger.Info("EC2 instance terminated successfully", "instance_id", instanceID)
 
 	case "detect":
-		// Use a default Terraform state file if none is provided
-		tfStateFile := "terraform.tfstate" // Default file
-		if len(args) >= 2 {
-			tfStateFile = args[1]
-		}
+		return c.detect(args[1:])
+
+	case "diff-state":
+		return c.diffState(args[1:])
+
+	default:
+		return fmt.Errorf("invalid action: %s. Use 'up', 'down', 'detect' or 'diff-state': %w", action, entities.ErrInvalidAction)
+	}
 
-		// Initialize AWS client for drift detection
-		awsClient, err := awsClient.NewLiveAWSClient(c.ctx, c.logger)
+	return nil
+}
+
+// detect parses the detect flags and runs drift detection against the given desired state.
+func (c *DriftCommand) detect(args []string) error {
+	flags := flag.NewFlagSet("detect", flag.ContinueOnError)
+	input := flags.String("input", inputTFState, "desired state input: tfstate, show-state or show-plan (terraform show -json output), or hcl (.tf configuration directories)")
+	addressPrefix := flags.String("address-prefix", "", "only detect drift for resources under this address, e.g. module.app")
+	discover := flags.String("discover", "", "discover every local state (including workspaces) under this directory")
+	s3Endpoint := flags.String("s3-endpoint", "", "override the S3 endpoint for s3:// states, e.g. a local S3-compatible server")
+	httpLock := flags.Bool("http-lock", false, "take the state lock while reading http:// states, failing if another operation holds it")
+	lockMode := flags.String("lock-mode", string(terraform.LockModeWarn), "what to do when a state is locked by a running operation: warn, wait or abort")
+	hclState := flags.String("hcl-state", "", "with -input=hcl, the state that pairs configuration with instance IDs (default: terraform.tfstate in the configuration directory)")
+	lockTimeout := flags.Duration("lock-timeout", 5*time.Minute, "how long -lock-mode=wait waits for a lock to be released")
+	ignoreTags := flags.String("ignore-tags", strings.Join(usecases.DefaultIgnoredTags, ","), "comma-separated tag key patterns whose drift is not reported, e.g. aws:*,kubernetes.io/*; empty compares every tag")
+	suppressionsFile := flags.String("suppressions", "", "YAML or JSON file of rules accepting known drift, which is counted but not reported")
+	severityPolicyFile := flags.String("severity-policy", "", "YAML or JSON file of rules rating drifted attributes low, medium, high or critical, tried before the defaults")
+	failOn := flags.String("fail-on", "", "fail when drift of this severity or higher is found: low, medium, high or critical")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", entities.ErrInvalidArguments, err)
+	}
+	mode, err := terraform.ParseLockMode(*lockMode)
+	if err != nil {
+		return err
+	}
+	lockPolicy := terraform.LockPolicy{Mode: mode, Timeout: *lockTimeout}
+
+	// Use a default Terraform state file if none is provided
+	tfStateFiles := []string{"terraform.tfstate"} // Default file
+	if *input == inputHCL {
+		tfStateFiles = []string{"."}
+	}
+	var stateLabels map[string]string
+	switch {
+	case *discover != "" && *input == inputHCL:
+		return fmt.Errorf("%w: -discover finds state files and cannot be combined with -input=hcl", entities.ErrInvalidArguments)
+	case *discover != "" && flags.NArg() > 0:
+		return fmt.Errorf("%w: -discover cannot be combined with state file arguments", entities.ErrInvalidArguments)
+	case *discover != "":
+		tfStateFiles, stateLabels, err = c.discoverStates(*discover)
 		if err != nil {
-			return fmt.Errorf("failed to create AWS client: %w", entities.ErrFailedToCreateEC2Client)
+			return err
 		}
-		c.awsClient = awsClient
+	case flags.NArg() >= 1:
+		tfStateFiles, err = expandStatePaths(flags.Args())
+		if err != nil {
+			return err
+		}
+	}
 
-		// Create the drift detector
-		c.detector = usecases.NewDriftDetector(awsClient, c.logger)
+	tfParser, err := c.newTFParser(*input, c.stateSources(*s3Endpoint, *httpLock), lockPolicy, *hclState)
+	if err != nil {
+		return err
+	}
 
-		// Perform drift detection
-		err = c.detector.DetectDrift(tfStateFile)
+	var threshold entities.Severity
+	if *failOn != "" {
+		threshold, err = entities.ParseSeverity(*failOn)
 		if err != nil {
-			return fmt.Errorf("%w: %v", entities.ErrDriftDetectionFailed, err)
+			return err
 		}
+	}
+	severityPolicy := usecases.DefaultSeverityPolicy()
+	if *severityPolicyFile != "" {
+		severityPolicy, err = usecases.LoadSeverityPolicy(*severityPolicyFile)
+		if err != nil {
+			return err
+		}
+	}
+	var suppressions *usecases.Suppressions
+	if *suppressionsFile != "" {
+		suppressions, err = usecases.LoadSuppressions(*suppressionsFile)
+		if err != nil {
+			return err
+		}
+	}
 
-		c.logger.Info("Drift detection completed successfully")
+	// Initialize AWS client for drift detection
+	awsClient, err := awsClient.NewLiveAWSClient(c.ctx, c.logger)
+	if err != nil {
+		return fmt.Errorf("failed to create AWS client: %w", entities.ErrFailedToCreateEC2Client)
+	}
+	c.awsClient = awsClient
+
+	// Create the drift detector
+	c.detector = usecases.NewDriftDetector(awsClient, c.logger,
+		usecases.WithTFParser(tfParser),
+		usecases.WithAddressPrefix(*addressPrefix),
+		usecases.WithStateLabels(stateLabels),
+		usecases.WithIgnoredTags(strings.Split(*ignoreTags, ",")),
+		usecases.WithSuppressions(suppressions),
+		usecases.WithSeverityPolicy(severityPolicy),
+	)
+
+	// Perform drift detection
+	result, err := c.detector.DetectDrift(tfStateFiles...)
+	if err != nil {
+		return fmt.Errorf("%w: %v", entities.ErrDriftDetectionFailed, err)
+	}
 
-	default:
-		return fmt.Errorf("invalid action: %s. Use 'up', 'down', or 'detect': %w", action, entities.ErrInvalidAction)
+	c.logger.Info("Drift detection completed successfully", "region", result.Region, "duration", result.EndTime.Sub(result.StartTime).Round(time.Millisecond), "risk_score", result.RiskScore)
+	if threshold != "" && result.MaxSeverity.Rank() >= threshold.Rank() {
+		return fmt.Errorf("%w: %s drift found, -fail-on=%s", entities.ErrSeverityThresholdExceeded, result.MaxSeverity, threshold)
 	}
+	return nil
+}
 
+// diffState parses the diff-state flags and compares two states resource by resource. No AWS
+// client is created.
+func (c *DriftCommand) diffState(args []string) error {
+	flags := flag.NewFlagSet("diff-state", flag.ContinueOnError)
+	input := flags.String("input", inputTFState, "state input: tfstate, show-state or show-plan (terraform show -json output), or hcl (.tf configuration directories)")
+	s3Endpoint := flags.String("s3-endpoint", "", "override the S3 endpoint for s3:// states, e.g. a local S3-compatible server")
+	lockMode := flags.String("lock-mode", string(terraform.LockModeWarn), "what to do when a state is locked by a running operation: warn, wait or abort")
+	lockTimeout := flags.Duration("lock-timeout", 5*time.Minute, "how long -lock-mode=wait waits for a lock to be released")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", entities.ErrInvalidArguments, err)
+	}
+	if flags.NArg() != 2 {
+		return fmt.Errorf("%w: diff-state takes exactly two states, the old one first", entities.ErrInvalidArguments)
+	}
+	mode, err := terraform.ParseLockMode(*lockMode)
+	if err != nil {
+		return err
+	}
+
+	tfParser, err := c.newTFParser(*input, c.stateSources(*s3Endpoint, false), terraform.LockPolicy{Mode: mode, Timeout: *lockTimeout}, "")
+	if err != nil {
+		return err
+	}
+
+	differ := usecases.NewStateDiffer(tfParser, c.logger)
+	if _, err := differ.DiffStates(flags.Arg(0), flags.Arg(1)); err != nil {
+		return fmt.Errorf("%w: %v", entities.ErrStateDiffFailed, err)
+	}
+
+	c.logger.Info("State diff completed successfully")
 	return nil
 }
 
+// discoverStates finds every local state under dir and labels each with its root path and workspace.
+func (c *DriftCommand) discoverStates(dir string) ([]string, map[string]string, error) {
+	states, err := terraform.DiscoverStates(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", entities.ErrInvalidArguments, err)
+	}
+	if len(states) == 0 {
+		return nil, nil, fmt.Errorf("%w: no terraform.tfstate files found under %s", entities.ErrInvalidArguments, dir)
+	}
+
+	paths := make([]string, 0, len(states))
+	labels := make(map[string]string, len(states))
+	for _, state := range states {
+		c.logger.Info("Discovered state", "root", state.Root, "workspace", state.Workspace, "path", state.Path)
+		paths = append(paths, state.Path)
+		labels[state.Path] = state.Label()
+	}
+	return paths, labels, nil
+}
+
+// expandStatePaths expands glob patterns among the state arguments, keeping plain paths as given.
+func expandStatePaths(args []string) ([]string, error) {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, arg := range args {
+		matches := []string{arg}
+		if !terraform.IsRemoteLocation(arg) && strings.ContainsAny(arg, "*?[") {
+			var err error
+			matches, err = filepath.Glob(arg)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid pattern %q: %v", entities.ErrInvalidArguments, arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("%w: no state files match %q", entities.ErrInvalidArguments, arg)
+			}
+		}
+		for _, path := range matches {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths, nil
+}
+
+// stateSources returns the sources states can be read from: local files, the S3 backend and the
+// http backend. The S3 client is only created once an s3:// location is actually read.
+func (c *DriftCommand) stateSources(s3Endpoint string, httpLock bool) *terraform.StateSources {
+	sources := terraform.NewStateSources()
+
+	httpConfig := terraform.HTTPBackendConfigFromEnv()
+	httpConfig.Lock = httpLock
+	sources.Register("http", terraform.HTTPSourceFactory(httpConfig))
+	sources.Register("https", terraform.HTTPSourceFactory(httpConfig))
+
+	var once sync.Once
+	var s3Client *awsSDK.S3Client
+	var s3Err error
+	sources.Register("s3", func(location *url.URL) (terraform.StateSource, error) {
+		once.Do(func() {
+			s3Client, s3Err = awsSDK.InitializeS3(c.ctx, s3Endpoint)
+		})
+		if s3Err != nil {
+			return nil, fmt.Errorf("failed to create S3 client: %w", s3Err)
+		}
+		return terraform.S3SourceFactory(s3Client)(location)
+	})
+
+	return sources
+}
+
+// Values accepted by the detect -input flag.
+const (
+	inputTFState   = "tfstate"
+	inputShowState = "show-state"
+	inputShowPlan  = "show-plan"
+	inputHCL       = "hcl"
+)
+
+// newTFParser returns the parser for the given -input value, reading states through sources.
+// The lock policy applies to state files; `terraform show -json` output is never locked. HCL
+// configuration is paired with instance IDs through hclState.
+func (c *DriftCommand) newTFParser(input string, sources *terraform.StateSources, lockPolicy terraform.LockPolicy, hclState string) (terraform.TFStateParser, error) {
+	switch input {
+	case inputTFState:
+		return terraform.NewTFStateParser(c.logger).WithSources(sources).WithLockPolicy(lockPolicy), nil
+	case inputHCL:
+		stateParser := terraform.NewTFStateParser(c.logger).WithSources(sources).WithLockPolicy(lockPolicy)
+		return terraform.NewHCLConfigParser(c.logger, stateParser, hclState), nil
+	case inputShowState:
+		return terraform.NewShowJSONParser(c.logger, terraform.ShowCurrentState).WithSources(sources), nil
+	case inputShowPlan:
+		return terraform.NewShowJSONParser(c.logger, terraform.ShowPlannedValues).WithSources(sources), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown -input %q, use %s, %s, %s or %s", entities.ErrInvalidArguments, input, inputTFState, inputShowState, inputShowPlan, inputHCL)
+	}
+}
+
 // createEC2Instance creates a new EC2 instance and returns its instance ID.
 func (c *DriftCommand) createEC2Instance(amiID, instanceType, subnetID, keyName string) (string, error) {
 	instanceID, err := c.ec2Client.CreateInstance(c.ctx, amiID, instanceType, subnetID, keyName)
@@ -15,6 +15,15 @@ var (
 	// ErrConfigComparison is returned when comparing AWS and Terraform configurations fails due to invalid data.
 	ErrConfigComparison = errors.New("failed to compare configurations")
 
+	// ErrUnsupportedStateSource is returned when a state location cannot be read by any registered source.
+	ErrUnsupportedStateSource = errors.New("unsupported state source")
+
+	// ErrUnsupportedStateVersion is returned when a state file uses a format version the parser cannot read.
+	ErrUnsupportedStateVersion = errors.New("unsupported state version")
+
+	// ErrStateLocked is returned when a Terraform state is locked by another operation.
+	ErrStateLocked = errors.New("state is locked")
+
 	// ErrEmptyConfigs is returned when no configurations are found in AWS or Terraform state.
 	ErrEmptyConfigs = errors.New("no configurations found")
 
@@ -51,9 +60,21 @@ var (
 	// ErrFailedToFetchAWSConfigs indicates failure in retrieving AWS configuration (e.g., credentials or region).
 	ErrFailedToFetchAWSConfigs = errors.New("failed to fetch AWS configuration")
 
+	// ErrInvalidArguments is returned when command-line flags or arguments are invalid.
+	ErrInvalidArguments = errors.New("invalid arguments")
+
 	// ErrMissingInstanceID indicates that the instance ID was not provided when required.
 	ErrMissingInstanceID = errors.New("please provide the instance ID to terminate")
 
 	// ErrDriftDetectionFailed indicates a failure during drift detection.
 	ErrDriftDetectionFailed = errors.New("drift detection failed")
+
+	// ErrStateDiffFailed indicates a failure while comparing two Terraform states.
+	ErrStateDiffFailed = errors.New("state diff failed")
+
+	// ErrSeverityThresholdExceeded is returned when drift at or above the -fail-on severity is found.
+	ErrSeverityThresholdExceeded = errors.New("drift severity threshold exceeded")
+
+	// ErrInvalidSuppressions is returned when a drift suppression file cannot be read or holds an invalid rule.
+	ErrInvalidSuppressions = errors.New("invalid suppression file")
 )
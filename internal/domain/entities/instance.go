@@ -1,5 +1,11 @@
 package entities
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 type InstanceConfig struct {
 	InstanceID         string            `json:"instance_id"`
 	InstanceType       string            `json:"instance_type"`
@@ -11,21 +17,177 @@ type InstanceConfig struct {
 	SubnetID           string            `json:"subnet_id"`
 	IAMInstanceProfile string            `json:"iam_instance_profile"`
 	EBSBlockDevices    []EBSBlockDevice  `json:"ebs_block_devices"`
+	// NetworkInterfaces are the secondary network interfaces; SecurityGroupIDs are the primary one's.
+	NetworkInterfaces []NetworkInterface `json:"network_interfaces,omitempty"`
+	State             string             `json:"state,omitempty"`
+}
+
+// InstanceStateTerminated and InstanceStateShuttingDown are the EC2 states of an instance that is gone or going.
+const (
+	InstanceStateTerminated   = "terminated"
+	InstanceStateShuttingDown = "shutting-down"
+)
+
+// IsTerminated reports whether AWS still returns the instance but it has been (or is being) terminated.
+func (c InstanceConfig) IsTerminated() bool {
+	return c.State == InstanceStateTerminated || c.State == InstanceStateShuttingDown
+}
+
+// DriftKind classifies how a resource differs between AWS and Terraform.
+type DriftKind string
+
+const (
+	// DriftModified is an instance managed by Terraform whose attributes differ from the state.
+	DriftModified DriftKind = "modified"
+
+	// DriftUnmanaged is an instance that exists in AWS but no Terraform resource claims its ID.
+	DriftUnmanaged DriftKind = "unmanaged"
+
+	// DriftMissing is an instance declared in Terraform state that AWS no longer returns, or returns terminated.
+	DriftMissing DriftKind = "missing"
+)
+
+// ChangeKind is how an attribute differs between two sides: Terraform and AWS when detecting
+// drift, or the old and the new state when diffing states.
+type ChangeKind string
+
+const (
+	// ChangeAdded is an attribute only the second side, AWS or the new state, holds.
+	ChangeAdded ChangeKind = "added"
+	// ChangeRemoved is an attribute only the first side, Terraform or the old state, holds.
+	ChangeRemoved ChangeKind = "removed"
+	// ChangeModified is an attribute both sides hold with different values.
+	ChangeModified ChangeKind = "modified"
+)
+
+// Severity ranks how much a drifted attribute matters, from low to critical.
+type Severity string
+
+const (
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+// severityRanks orders the severities; an unknown or empty severity ranks below low.
+var severityRanks = map[Severity]int{SeverityLow: 1, SeverityMedium: 2, SeverityHigh: 3, SeverityCritical: 4}
+
+// Rank returns the position of s from 1 (low) to 4 (critical), or 0 when s is not a severity.
+func (s Severity) Rank() int {
+	return severityRanks[s]
+}
+
+// ParseSeverity parses low, medium, high or critical.
+func ParseSeverity(s string) (Severity, error) {
+	severity := Severity(strings.ToLower(s))
+	if severity.Rank() == 0 {
+		return "", fmt.Errorf("%w: severity must be low, medium, high or critical, got %q", ErrInvalidArguments, s)
+	}
+	return severity, nil
 }
 
 type EBSBlockDevice struct {
 	DeviceName string `json:"device_name"`
 	VolumeSize int    `json:"volume_size"`
 	VolumeType string `json:"volume_type"`
+	Iops       int    `json:"iops,omitempty"`
+	Throughput int    `json:"throughput,omitempty"`
+	Encrypted  bool   `json:"encrypted,omitempty"`
+	KMSKeyID   string `json:"kms_key_id,omitempty"`
+	VolumeID   string `json:"volume_id,omitempty"`
+	// Root marks the instance's root device; the others are additional EBS volumes.
+	Root bool `json:"root,omitempty"`
+}
+
+// NetworkInterface is a network interface attached to an instance at DeviceIndex.
+type NetworkInterface struct {
+	ID               string   `json:"network_interface_id"`
+	DeviceIndex      int      `json:"device_index"`
+	SecurityGroupIDs []string `json:"security_group_ids"`
+}
+
+// Lookups map the alternative identifiers AWS accepts for some attributes to the ones it returns,
+// so that equivalent values compare equal.
+type Lookups struct {
+	// SecurityGroupIDs maps security group names to IDs. Names used by groups in more than one
+	// VPC are left out, since they cannot be resolved.
+	SecurityGroupIDs map[string]string
+	// ZoneNames maps availability zone IDs, e.g. use1-az1, to names, e.g. us-east-1a.
+	ZoneNames map[string]string
 }
 
+// DriftReport is the drift detected for one instance. Changes is keyed by attribute path, e.g.
+// instance_type, tags.Name or ebs_block_device["/dev/sdf"].volume_size, and is empty for
+// unmanaged and missing instances.
 type DriftReport struct {
-	InstanceID string            `json:"instance_id"`
-	HasDrift   bool              `json:"has_drift"`
-	Changes    map[string]Change `json:"changes"`
+	InstanceID string `json:"instance_id"`
+	// Address is the Terraform resource managing the instance, empty when no resource claims it.
+	Address string `json:"address,omitempty"`
+	// Source is the state the resource was read from, set when several states are merged.
+	Source   string    `json:"source,omitempty"`
+	Kind     DriftKind `json:"kind,omitempty"`
+	HasDrift bool      `json:"has_drift"`
+	// Severity is the highest severity of the report's changes, or of its kind for unmanaged and
+	// missing instances; empty without drift.
+	Severity Severity          `json:"severity,omitempty"`
+	Changes  map[string]Change `json:"changes"`
+	// Suppressed maps the drifted fields a suppression rule accepts to the rule's justification.
+	// They are left out of Changes. A suppressed unmanaged or missing instance is keyed by its kind.
+	Suppressed map[string]string `json:"suppressed,omitempty"`
 }
 
+// Change is the desired and live value of one drifted attribute; Expected is nil when only AWS
+// sets it and Actual when only Terraform does. Both values are withheld when the state marks the
+// attribute as sensitive.
 type Change struct {
 	Expected interface{} `json:"expected"`
 	Actual   interface{} `json:"actual"`
+	Kind     ChangeKind  `json:"kind,omitempty"`
+	// Attached and Detached list the members of a set, such as security group IDs, only in AWS
+	// and only in Terraform respectively.
+	Attached  []string `json:"attached,omitempty"`
+	Detached  []string `json:"detached,omitempty"`
+	Severity  Severity `json:"severity,omitempty"`
+	Sensitive bool     `json:"sensitive,omitempty"`
+}
+
+// DriftResult is the outcome of one drift detection run.
+type DriftResult struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	// States are the states compared against, by label when they were given one, with the
+	// revision of each that was read.
+	States []StateVersion `json:"states"`
+	// Region is the AWS region the instances were read from, when the client reports it.
+	Region string `json:"region,omitempty"`
+	// Reports holds one report per compared, unmanaged and missing instance.
+	Reports []DriftReport `json:"reports"`
+	Counts  DriftCounts   `json:"counts"`
+	// RiskScore weighs every reported change, unmanaged and missing instance by its severity.
+	RiskScore int `json:"risk_score"`
+	// MaxSeverity is the highest severity of any report, empty without drift.
+	MaxSeverity Severity `json:"max_severity,omitempty"`
+}
+
+// StateVersion identifies the revision of a state that was read: Terraform increments serial on
+// every write and keeps lineage for the life of the state. Serial and lineage are unset for inputs
+// that do not record them, such as `terraform show -json` output.
+type StateVersion struct {
+	Source           string `json:"source"`
+	Serial           int    `json:"serial"`
+	Lineage          string `json:"lineage,omitempty"`
+	TerraformVersion string `json:"terraform_version,omitempty"`
+}
+
+// DriftCounts summarises a drift detection run.
+type DriftCounts struct {
+	Compared  int `json:"compared"`
+	Modified  int `json:"modified"`
+	Unmanaged int `json:"unmanaged"`
+	Missing   int `json:"missing"`
+	Conflicts int `json:"conflicts"`
+	// Suppressed counts the drifted fields, and unmanaged or missing instances, that suppression
+	// rules accept. They are not counted as modified, unmanaged or missing.
+	Suppressed int `json:"suppressed"`
 }
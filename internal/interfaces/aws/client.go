@@ -33,23 +33,181 @@ func (c *LiveAWSClient) FetchInstanceConfigs() ([]entities.InstanceConfig, error
 	c.logger.Info("Fetching EC2 instance configurations from AWS")
 
 	// Use the underlying ec2.Client directly to fetch all instances
-	input := &aws.DescribeInstancesInput{}
-	result, err := c.ec2Client.(*EC2ClientImpl).client.DescribeInstances(context.Background(), input)
+	instances, err := describeInstances(context.Background(), c.ec2Client.(*EC2ClientImpl).client)
 	if err != nil {
 		c.logger.Error("Failed to describe EC2 instances", "error", err)
 		return nil, fmt.Errorf("failed to describe EC2 instances: %w", entities.ErrFailedToFetchAWSConfigs)
 	}
 
 	var configs []entities.InstanceConfig
-	for _, reservation := range result.Reservations {
-		for _, instance := range reservation.Instances {
-			config := c.ec2Client.ToInstanceConfig(&instance)
-			config.InstanceID = aws.ToString(instance.InstanceId) // Use aws.ToString from pkg/aws
-			configs = append(configs, config)
-			c.logger.Info("Fetched instance", "instance_id", config.InstanceID)
-		}
+	for _, instance := range instances {
+		config := c.ec2Client.ToInstanceConfig(&instance)
+		config.InstanceID = aws.ToString(instance.InstanceId) // Use aws.ToString from pkg/aws
+		configs = append(configs, config)
+		c.logger.Info("Fetched instance", "instance_id", config.InstanceID)
+	}
+
+	if err := enrichVolumes(context.Background(), c.ec2Client.Client(), configs, c.logger); err != nil {
+		c.logger.Error("Failed to describe EBS volumes", "error", err)
+		return nil, fmt.Errorf("%w: %v", entities.ErrFailedToFetchAWSConfigs, err)
 	}
 
 	c.logger.Info("Completed fetching EC2 instance configurations", "count", len(configs))
 	return configs, nil
 }
+
+// instanceDescriber is the part of the EC2 API that lists instances.
+type instanceDescriber interface {
+	DescribeInstances(ctx context.Context, params *aws.DescribeInstancesInput, optFns ...func(*aws.EC2Options)) (*aws.DescribeInstancesOutput, error)
+}
+
+// describeInstances returns the instances of every DescribeInstances page. An instance left out
+// would be reported as missing, so a failure on any page fails the whole listing.
+func describeInstances(ctx context.Context, api instanceDescriber) ([]aws.Instance, error) {
+	var instances []aws.Instance
+	input := &aws.DescribeInstancesInput{}
+	for {
+		output, err := api.DescribeInstances(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, reservation := range output.Reservations {
+			instances = append(instances, reservation.Instances...)
+		}
+		if aws.ToString(output.NextToken) == "" {
+			return instances, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
+
+// volumeBatchSize is how many volume IDs one DescribeVolumes call filters on, the API's limit
+// for filter values.
+const volumeBatchSize = 200
+
+// volumeDescriber is the part of the EC2 API that resolves block device volumes.
+type volumeDescriber interface {
+	DescribeVolumes(ctx context.Context, params *aws.DescribeVolumesInput, optFns ...func(*aws.EC2Options)) (*aws.DescribeVolumesOutput, error)
+}
+
+// enrichVolumes fills the size, type, IOPS, throughput and encryption of every EBS block device
+// from DescribeVolumes, batching the volume IDs of all instances. The volume-id filter is used
+// rather than VolumeIds so a volume deleted since DescribeInstances does not fail the batch; such
+// devices are dropped.
+func enrichVolumes(ctx context.Context, api volumeDescriber, configs []entities.InstanceConfig, log logger.Logger) error {
+	var ids []string
+	for _, config := range configs {
+		for _, device := range config.EBSBlockDevices {
+			ids = append(ids, device.VolumeID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	volumes := make(map[string]aws.Volume, len(ids))
+	for start := 0; start < len(ids); start += volumeBatchSize {
+		end := min(start+volumeBatchSize, len(ids))
+		input := &aws.DescribeVolumesInput{
+			Filters: []aws.Filter{{Name: aws.String("volume-id"), Values: ids[start:end]}},
+		}
+		for {
+			output, err := api.DescribeVolumes(ctx, input)
+			if err != nil {
+				return fmt.Errorf("failed to describe volumes: %w", err)
+			}
+			for _, volume := range output.Volumes {
+				volumes[aws.ToString(volume.VolumeId)] = volume
+			}
+			if aws.ToString(output.NextToken) == "" {
+				break
+			}
+			input.NextToken = output.NextToken
+		}
+	}
+	log.Info("Fetched EBS volumes", "count", len(volumes))
+
+	for i := range configs {
+		var devices []entities.EBSBlockDevice
+		for _, device := range configs[i].EBSBlockDevices {
+			volume, ok := volumes[device.VolumeID]
+			if !ok {
+				log.Warn("EBS volume not found, skipping block device", "instance_id", configs[i].InstanceID, "device_name", device.DeviceName, "volume_id", device.VolumeID)
+				continue
+			}
+			device.VolumeSize = int(aws.ToInt32(volume.Size))
+			device.VolumeType = string(volume.VolumeType)
+			device.Iops = int(aws.ToInt32(volume.Iops))
+			device.Throughput = int(aws.ToInt32(volume.Throughput))
+			device.Encrypted = aws.ToBool(volume.Encrypted)
+			device.KMSKeyID = aws.ToString(volume.KmsKeyId)
+			devices = append(devices, device)
+		}
+		configs[i].EBSBlockDevices = devices
+	}
+	return nil
+}
+
+// Lookups resolves the security group names and availability zone IDs of the region, which
+// Terraform may use in place of the group IDs and zone names AWS returns for instances.
+func (c *LiveAWSClient) Lookups() (entities.Lookups, error) {
+	lookups, err := fetchLookups(context.Background(), c.ec2Client.Client())
+	if err != nil {
+		c.logger.Error("Failed to resolve security groups and availability zones", "error", err)
+		return entities.Lookups{}, err
+	}
+	c.logger.Info("Fetched lookups", "security_groups", len(lookups.SecurityGroupIDs), "availability_zones", len(lookups.ZoneNames))
+	return lookups, nil
+}
+
+// lookupDescriber is the part of the EC2 API that resolves alternative identifiers.
+type lookupDescriber interface {
+	DescribeSecurityGroups(ctx context.Context, params *aws.DescribeSecurityGroupsInput, optFns ...func(*aws.EC2Options)) (*aws.DescribeSecurityGroupsOutput, error)
+	DescribeAvailabilityZones(ctx context.Context, params *aws.DescribeAvailabilityZonesInput, optFns ...func(*aws.EC2Options)) (*aws.DescribeAvailabilityZonesOutput, error)
+}
+
+// fetchLookups maps security group names to IDs and availability zone IDs to names.
+func fetchLookups(ctx context.Context, api lookupDescriber) (entities.Lookups, error) {
+	lookups := entities.Lookups{
+		SecurityGroupIDs: make(map[string]string),
+		ZoneNames:        make(map[string]string),
+	}
+
+	ambiguous := make(map[string]bool)
+	input := &aws.DescribeSecurityGroupsInput{}
+	for {
+		output, err := api.DescribeSecurityGroups(ctx, input)
+		if err != nil {
+			return entities.Lookups{}, fmt.Errorf("failed to describe security groups: %w", err)
+		}
+		for _, group := range output.SecurityGroups {
+			name, id := aws.ToString(group.GroupName), aws.ToString(group.GroupId)
+			// Every VPC has a group named default, so names are only unique within a VPC
+			if existing, ok := lookups.SecurityGroupIDs[name]; ok && existing != id {
+				ambiguous[name] = true
+			}
+			lookups.SecurityGroupIDs[name] = id
+		}
+		if aws.ToString(output.NextToken) == "" {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+	for name := range ambiguous {
+		delete(lookups.SecurityGroupIDs, name)
+	}
+
+	zones, err := api.DescribeAvailabilityZones(ctx, &aws.DescribeAvailabilityZonesInput{})
+	if err != nil {
+		return entities.Lookups{}, fmt.Errorf("failed to describe availability zones: %w", err)
+	}
+	for _, zone := range zones.AvailabilityZones {
+		lookups.ZoneNames[aws.ToString(zone.ZoneId)] = aws.ToString(zone.ZoneName)
+	}
+	return lookups, nil
+}
+
+// Region returns the AWS region instances are read from.
+func (c *LiveAWSClient) Region() string {
+	return c.ec2Client.Client().Options().Region
+}
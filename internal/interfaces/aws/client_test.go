@@ -1 +1,154 @@
 package aws
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+	"github.com/cstudio7/drift-detector/internal/interfaces/logger"
+	"github.com/cstudio7/drift-detector/pkg/aws"
+)
+
+// fakeInstanceDescriber serves DescribeInstances in pages of one reservation.
+type fakeInstanceDescriber struct {
+	reservations []ec2types.Reservation
+	calls        int
+}
+
+func (f *fakeInstanceDescriber) DescribeInstances(ctx context.Context, params *aws.DescribeInstancesInput, optFns ...func(*aws.EC2Options)) (*aws.DescribeInstancesOutput, error) {
+	f.calls++
+	page := 0
+	if params.NextToken != nil {
+		fmt.Sscan(*params.NextToken, &page)
+	}
+	output := &aws.DescribeInstancesOutput{Reservations: f.reservations[page : page+1]}
+	if page+1 < len(f.reservations) {
+		output.NextToken = aws.String(fmt.Sprint(page + 1))
+	}
+	return output, nil
+}
+
+func TestDescribeInstances(t *testing.T) {
+	fake := &fakeInstanceDescriber{reservations: []ec2types.Reservation{
+		{Instances: []aws.Instance{{InstanceId: aws.String("i-1")}, {InstanceId: aws.String("i-2")}}},
+		{Instances: []aws.Instance{{InstanceId: aws.String("i-3")}}},
+		{Instances: []aws.Instance{{InstanceId: aws.String("i-4")}}},
+	}}
+
+	instances, err := describeInstances(context.Background(), fake)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if fake.calls != 3 {
+		t.Errorf("Expected 3 DescribeInstances calls, got %d", fake.calls)
+	}
+	var ids []string
+	for _, instance := range instances {
+		ids = append(ids, aws.ToString(instance.InstanceId))
+	}
+	if fmt.Sprint(ids) != "[i-1 i-2 i-3 i-4]" {
+		t.Errorf("Expected the instances of every page, got %v", ids)
+	}
+}
+
+// fakeVolumeDescriber serves DescribeVolumes from a fixed set of volumes, recording each batch.
+type fakeVolumeDescriber struct {
+	volumes map[string]aws.Volume
+	batches [][]string
+}
+
+func (f *fakeVolumeDescriber) DescribeVolumes(ctx context.Context, params *aws.DescribeVolumesInput, optFns ...func(*aws.EC2Options)) (*aws.DescribeVolumesOutput, error) {
+	ids := params.Filters[0].Values
+	f.batches = append(f.batches, ids)
+	output := &aws.DescribeVolumesOutput{}
+	for _, id := range ids {
+		if volume, ok := f.volumes[id]; ok {
+			output.Volumes = append(output.Volumes, volume)
+		}
+	}
+	return output, nil
+}
+
+func TestEnrichVolumes(t *testing.T) {
+	fake := &fakeVolumeDescriber{volumes: make(map[string]aws.Volume)}
+	var configs []entities.InstanceConfig
+	for i := 0; i < 225; i++ {
+		rootID, dataID := fmt.Sprintf("vol-root%d", i), fmt.Sprintf("vol-data%d", i)
+		fake.volumes[rootID] = aws.Volume{VolumeId: aws.String(rootID), Size: aws.Int32(8), VolumeType: ec2types.VolumeTypeGp3, Iops: aws.Int32(3000), Throughput: aws.Int32(125)}
+		fake.volumes[dataID] = aws.Volume{VolumeId: aws.String(dataID), Size: aws.Int32(100), VolumeType: ec2types.VolumeTypeIo2, Iops: aws.Int32(5000), Encrypted: aws.Bool(true), KmsKeyId: aws.String("arn:aws:kms:us-east-1:123456789012:key/abc")}
+		configs = append(configs, entities.InstanceConfig{
+			InstanceID: fmt.Sprintf("i-%d", i),
+			EBSBlockDevices: []entities.EBSBlockDevice{
+				{DeviceName: "/dev/xvda", VolumeID: rootID, Root: true},
+				{DeviceName: "/dev/sdf", VolumeID: dataID},
+			},
+		})
+	}
+	// A volume deleted since DescribeInstances is dropped rather than compared empty
+	delete(fake.volumes, "vol-data7")
+
+	if err := enrichVolumes(context.Background(), fake, configs, logger.NewTestLogger()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(fake.batches) != 3 || len(fake.batches[0]) != 200 || len(fake.batches[2]) != 50 {
+		t.Errorf("Expected batches of 200, 200 and 50 volume IDs, got %d batches", len(fake.batches))
+	}
+	root := configs[0].EBSBlockDevices[0]
+	if root.VolumeSize != 8 || root.VolumeType != "gp3" || root.Iops != 3000 || root.Throughput != 125 || !root.Root {
+		t.Errorf("Expected root device 8 GiB gp3 with 3000 IOPS and 125 MiB/s, got %+v", root)
+	}
+	data := configs[0].EBSBlockDevices[1]
+	if data.VolumeSize != 100 || data.VolumeType != "io2" || !data.Encrypted || data.KMSKeyID != "arn:aws:kms:us-east-1:123456789012:key/abc" {
+		t.Errorf("Expected encrypted 100 GiB io2 data device, got %+v", data)
+	}
+	if len(configs[7].EBSBlockDevices) != 1 {
+		t.Errorf("Expected the deleted volume to be dropped, got %+v", configs[7].EBSBlockDevices)
+	}
+}
+
+// fakeLookupDescriber serves DescribeSecurityGroups in pages of one group.
+type fakeLookupDescriber struct {
+	groups []aws.SecurityGroup
+	zones  []aws.AvailabilityZone
+}
+
+func (f *fakeLookupDescriber) DescribeSecurityGroups(ctx context.Context, params *aws.DescribeSecurityGroupsInput, optFns ...func(*aws.EC2Options)) (*aws.DescribeSecurityGroupsOutput, error) {
+	page := 0
+	if params.NextToken != nil {
+		fmt.Sscan(*params.NextToken, &page)
+	}
+	output := &aws.DescribeSecurityGroupsOutput{SecurityGroups: f.groups[page : page+1]}
+	if page+1 < len(f.groups) {
+		output.NextToken = aws.String(fmt.Sprint(page + 1))
+	}
+	return output, nil
+}
+
+func (f *fakeLookupDescriber) DescribeAvailabilityZones(ctx context.Context, params *aws.DescribeAvailabilityZonesInput, optFns ...func(*aws.EC2Options)) (*aws.DescribeAvailabilityZonesOutput, error) {
+	return &aws.DescribeAvailabilityZonesOutput{AvailabilityZones: f.zones}, nil
+}
+
+func TestFetchLookups(t *testing.T) {
+	fake := &fakeLookupDescriber{
+		groups: []aws.SecurityGroup{
+			{GroupName: aws.String("web"), GroupId: aws.String("sg-1")},
+			{GroupName: aws.String("default"), GroupId: aws.String("sg-2")},
+			{GroupName: aws.String("default"), GroupId: aws.String("sg-3")},
+		},
+		zones: []aws.AvailabilityZone{{ZoneId: aws.String("use1-az1"), ZoneName: aws.String("us-east-1b")}},
+	}
+
+	lookups, err := fetchLookups(context.Background(), fake)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(lookups.SecurityGroupIDs) != 1 || lookups.SecurityGroupIDs["web"] != "sg-1" {
+		t.Errorf("Expected only the unambiguous group name web to resolve, got %v", lookups.SecurityGroupIDs)
+	}
+	if lookups.ZoneNames["use1-az1"] != "us-east-1b" {
+		t.Errorf("Expected use1-az1 to resolve to us-east-1b, got %v", lookups.ZoneNames)
+	}
+}
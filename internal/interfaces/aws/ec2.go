@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 
 	"encoding/json"
 	"github.com/cstudio7/drift-detector/internal/domain/entities"
@@ -190,12 +191,58 @@ func (c *EC2ClientImpl) ToInstanceConfig(instance *aws.Instance) entities.Instan
 			config.SecurityGroupIDs = append(config.SecurityGroupIDs, *sg.GroupId)
 		}
 	}
+	// Terraform reads vpc_security_group_ids from the primary network interface; the groups of
+	// secondary interfaces are kept apart so they are compared with their own interface
+	for _, eni := range instance.NetworkInterfaces {
+		if eni.Attachment == nil || eni.NetworkInterfaceId == nil {
+			continue
+		}
+		var groups []string
+		for _, sg := range eni.Groups {
+			if sg.GroupId != nil {
+				groups = append(groups, *sg.GroupId)
+			}
+		}
+		deviceIndex := int(aws.ToInt32(eni.Attachment.DeviceIndex))
+		if deviceIndex == 0 {
+			config.SecurityGroupIDs = groups
+			continue
+		}
+		config.NetworkInterfaces = append(config.NetworkInterfaces, entities.NetworkInterface{
+			ID:               *eni.NetworkInterfaceId,
+			DeviceIndex:      deviceIndex,
+			SecurityGroupIDs: groups,
+		})
+	}
+	sort.Slice(config.NetworkInterfaces, func(i, j int) bool {
+		return config.NetworkInterfaces[i].DeviceIndex < config.NetworkInterfaces[j].DeviceIndex
+	})
 	if instance.SubnetId != nil {
 		config.SubnetID = *instance.SubnetId
 	}
+	config.AMI = aws.ToString(instance.ImageId)
+	config.KeyName = aws.ToString(instance.KeyName)
+	if instance.Placement != nil {
+		config.AvailabilityZone = aws.ToString(instance.Placement.AvailabilityZone)
+	}
 	if instance.IamInstanceProfile != nil && instance.IamInstanceProfile.Arn != nil {
 		config.IAMInstanceProfile = *instance.IamInstanceProfile.Arn
 	}
+	if instance.State != nil {
+		config.State = string(instance.State.Name)
+	}
+	// DescribeInstances only returns volume IDs; the volumes themselves are resolved with DescribeVolumes
+	for _, mapping := range instance.BlockDeviceMappings {
+		if mapping.Ebs == nil || mapping.Ebs.VolumeId == nil {
+			continue
+		}
+		deviceName := aws.ToString(mapping.DeviceName)
+		config.EBSBlockDevices = append(config.EBSBlockDevices, entities.EBSBlockDevice{
+			DeviceName: deviceName,
+			VolumeID:   *mapping.Ebs.VolumeId,
+			Root:       deviceName == aws.ToString(instance.RootDeviceName),
+		})
+	}
 	return config
 }
 
@@ -79,6 +79,15 @@ func TestEC2ClientImpl_ToInstanceConfig(t *testing.T) {
 		IamInstanceProfile: &types.IamInstanceProfile{
 			Arn: aws.String("arn:aws:iam::123456789012:instance-profile/test-profile"),
 		},
+		State:          &types.InstanceState{Name: types.InstanceStateNameRunning},
+		ImageId:        aws.String("ami-0c55b159cbfafe1f0"),
+		KeyName:        aws.String("deploy"),
+		Placement:      &types.Placement{AvailabilityZone: aws.String("us-east-1a")},
+		RootDeviceName: aws.String("/dev/xvda"),
+		BlockDeviceMappings: []types.InstanceBlockDeviceMapping{
+			{DeviceName: aws.String("/dev/xvda"), Ebs: &types.EbsInstanceBlockDevice{VolumeId: aws.String("vol-root")}},
+			{DeviceName: aws.String("/dev/sdf"), Ebs: &types.EbsInstanceBlockDevice{VolumeId: aws.String("vol-data")}},
+		},
 	}
 
 	// Create the EC2 client
@@ -105,4 +114,53 @@ func TestEC2ClientImpl_ToInstanceConfig(t *testing.T) {
 	if config.IAMInstanceProfile != "arn:aws:iam::123456789012:instance-profile/test-profile" {
 		t.Errorf("Expected IAMInstanceProfile arn:aws:iam::123456789012:instance-profile/test-profile, got %s", config.IAMInstanceProfile)
 	}
+	if config.State != "running" {
+		t.Errorf("Expected State running, got %s", config.State)
+	}
+	if config.AMI != "ami-0c55b159cbfafe1f0" {
+		t.Errorf("Expected AMI ami-0c55b159cbfafe1f0, got %s", config.AMI)
+	}
+	if config.AvailabilityZone != "us-east-1a" {
+		t.Errorf("Expected AvailabilityZone us-east-1a, got %s", config.AvailabilityZone)
+	}
+	if config.KeyName != "deploy" {
+		t.Errorf("Expected KeyName deploy, got %s", config.KeyName)
+	}
+	if len(config.EBSBlockDevices) != 2 || !config.EBSBlockDevices[0].Root || config.EBSBlockDevices[1].Root || config.EBSBlockDevices[1].VolumeID != "vol-data" {
+		t.Errorf("Expected root device vol-root and additional device vol-data, got %+v", config.EBSBlockDevices)
+	}
+}
+
+func TestEC2ClientImpl_ToInstanceConfig_NetworkInterfaces(t *testing.T) {
+	instance := &types.Instance{
+		InstanceId: aws.String("i-multi"),
+		SecurityGroups: []types.GroupIdentifier{
+			{GroupId: aws.String("sg-web")}, {GroupId: aws.String("sg-db")},
+		},
+		NetworkInterfaces: []types.InstanceNetworkInterface{
+			{
+				NetworkInterfaceId: aws.String("eni-db"),
+				Attachment:         &types.InstanceNetworkInterfaceAttachment{DeviceIndex: aws.Int32(1)},
+				Groups:             []types.GroupIdentifier{{GroupId: aws.String("sg-db")}},
+			},
+			{
+				NetworkInterfaceId: aws.String("eni-primary"),
+				Attachment:         &types.InstanceNetworkInterfaceAttachment{DeviceIndex: aws.Int32(0)},
+				Groups:             []types.GroupIdentifier{{GroupId: aws.String("sg-web")}},
+			},
+		},
+	}
+
+	client, err := NewEC2Client(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Failed to create EC2 client: %v", err)
+	}
+
+	config := client.ToInstanceConfig(instance)
+	if len(config.SecurityGroupIDs) != 1 || config.SecurityGroupIDs[0] != "sg-web" {
+		t.Errorf("Expected the primary interface's groups [sg-web], got %v", config.SecurityGroupIDs)
+	}
+	if len(config.NetworkInterfaces) != 1 || config.NetworkInterfaces[0].ID != "eni-db" || config.NetworkInterfaces[0].DeviceIndex != 1 || config.NetworkInterfaces[0].SecurityGroupIDs[0] != "sg-db" {
+		t.Errorf("Expected secondary interface eni-db at device index 1 with [sg-db], got %+v", config.NetworkInterfaces)
+	}
 }
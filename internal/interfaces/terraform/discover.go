@@ -0,0 +1,70 @@
+package terraform
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	stateFileName      = "terraform.tfstate"
+	workspacesDirName  = "terraform.tfstate.d"
+	terragruntCacheDir = ".terragrunt-cache"
+	defaultWorkspace   = "default"
+)
+
+// DiscoveredState is a local state file found under a Terraform or Terragrunt repository tree.
+type DiscoveredState struct {
+	Path      string `json:"path"`
+	Root      string `json:"root"`
+	Workspace string `json:"workspace"`
+}
+
+// Label identifies the state by its root module path and workspace.
+func (s DiscoveredState) Label() string {
+	return fmt.Sprintf("%s (workspace %s)", s.Root, s.Workspace)
+}
+
+// DiscoverStates walks dir and returns every local state in lexical path order: the terraform.tfstate
+// of each root module or Terragrunt unit, and terraform.tfstate.d/<workspace>/terraform.tfstate for
+// non-default workspaces. Roots are reported relative to dir; states inside a Terragrunt cache are
+// attributed to the unit that owns the cache.
+func DiscoverStates(dir string) ([]DiscoveredState, error) {
+	var states []DiscoveredState
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			// .terraform/terraform.tfstate holds backend settings, not resources
+			if name := entry.Name(); path != dir && (name == ".terraform" || name == ".git") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.Name() != stateFileName {
+			return nil
+		}
+
+		stateDir := filepath.Dir(path)
+		root, workspace := stateDir, defaultWorkspace
+		if filepath.Base(filepath.Dir(stateDir)) == workspacesDirName {
+			root, workspace = filepath.Dir(filepath.Dir(stateDir)), filepath.Base(stateDir)
+		}
+		if i := strings.Index(root, string(filepath.Separator)+terragruntCacheDir); i >= 0 {
+			root = root[:i]
+		}
+		rel, err := filepath.Rel(dir, root)
+		if err != nil {
+			return err
+		}
+
+		states = append(states, DiscoveredState{Path: path, Root: filepath.ToSlash(rel), Workspace: workspace})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover states under %s: %w", dir, err)
+	}
+	return states, nil
+}
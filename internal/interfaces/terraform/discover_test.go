@@ -0,0 +1,43 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscoverStates(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{
+		"terraform.tfstate",
+		"network/terraform.tfstate",
+		"network/terraform.tfstate.backup",
+		"network/.terraform/terraform.tfstate",
+		"app/terraform.tfstate.d/staging/terraform.tfstate",
+		"app/terraform.tfstate.d/prod/terraform.tfstate",
+		"live/eu/db/.terragrunt-cache/abc/def/terraform.tfstate",
+		"modules/web/main.tf",
+	} {
+		full := filepath.Join(dir, path)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(full), 0o755))
+		assert.NoError(t, os.WriteFile(full, []byte("{}"), 0o644))
+	}
+
+	states, err := DiscoverStates(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []DiscoveredState{
+		{Path: filepath.Join(dir, "app/terraform.tfstate.d/prod/terraform.tfstate"), Root: "app", Workspace: "prod"},
+		{Path: filepath.Join(dir, "app/terraform.tfstate.d/staging/terraform.tfstate"), Root: "app", Workspace: "staging"},
+		{Path: filepath.Join(dir, "live/eu/db/.terragrunt-cache/abc/def/terraform.tfstate"), Root: "live/eu/db", Workspace: "default"},
+		{Path: filepath.Join(dir, "network/terraform.tfstate"), Root: "network", Workspace: "default"},
+		{Path: filepath.Join(dir, "terraform.tfstate"), Root: ".", Workspace: "default"},
+	}, states)
+	assert.Equal(t, "app (workspace staging)", states[1].Label())
+}
+
+func TestDiscoverStates_MissingDir(t *testing.T) {
+	_, err := DiscoverStates(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,556 @@
+package terraform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+	"github.com/cstudio7/drift-detector/internal/interfaces/logger"
+)
+
+// FormatHCL is desired state read from .tf configuration rather than from a state.
+const FormatHCL StateFormat = "hcl"
+
+// computedAttributes are chosen by AWS when the configuration leaves them out, so an
+// unset value is unknown rather than empty.
+var computedAttributes = []string{"availability_zone", "subnet_id", "vpc_security_group_ids", "security_groups", "root_block_device"}
+
+// HCLConfigParser is a TFStateParser that reads the desired state from the aws_instance blocks of a
+// root module's .tf files. Configuration carries no instance IDs, so each block is paired with the
+// instances its address has in the module's state; only the IDs are taken from the state.
+type HCLConfigParser struct {
+	logger      logger.Logger
+	stateParser TFStateParser
+	statePath   string
+}
+
+// NewHCLConfigParser creates a new HCLConfigParser that reads instance IDs through stateParser from
+// statePath, or from terraform.tfstate in the configuration directory when statePath is empty.
+func NewHCLConfigParser(logger logger.Logger, stateParser TFStateParser, statePath string) *HCLConfigParser {
+	return &HCLConfigParser{
+		logger:      logger,
+		stateParser: stateParser,
+		statePath:   statePath,
+	}
+}
+
+// hclInstance is the desired configuration of one aws_instance block.
+type hclInstance struct {
+	config  entities.InstanceConfig
+	unknown []string
+	// rootDeviceUnnamed is set when root_block_device is configured, which never names its device.
+	rootDeviceUnnamed bool
+}
+
+// ParseTFState reads the aws_instance blocks of the .tf files in dir, a directory or a single file,
+// and returns one InstanceResource per instance the state records for each block.
+func (p *HCLConfigParser) ParseTFState(dir string) (InstanceConfigSet, error) {
+	p.logger.Info("Starting to parse HCL configuration", "path", dir)
+
+	files, statePath, err := p.configFiles(dir)
+	if err != nil {
+		p.logger.Error("Failed to read HCL configuration", "path", dir, "error", err.Error())
+		return InstanceConfigSet{}, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	blocks := make(map[string]hclInstance)
+	var defaults defaultTags
+	for _, file := range files {
+		if err := parseHCLFile(file, blocks, &defaults); err != nil {
+			p.logger.Error("Failed to parse HCL configuration", "file_path", file, "error", err.Error())
+			return InstanceConfigSet{}, err
+		}
+	}
+	p.logger.Info("Parsed HCL configuration", "files", len(files), "aws_instances", len(blocks))
+	if contains(defaults.unknown, "tags") {
+		p.logger.Warn("Provider default_tags are not literal, tags will not be compared", "path", dir)
+	}
+
+	state, err := p.stateParser.ParseTFState(statePath)
+	if err != nil {
+		p.logger.Error("Failed to read state for instance IDs", "file_path", statePath, "error", err.Error())
+		return InstanceConfigSet{}, fmt.Errorf("failed to read state for instance IDs: %w", err)
+	}
+
+	configSet := InstanceConfigSet{
+		Format:    FormatHCL,
+		Instances: make(map[string]InstanceResource),
+		States:    state.States,
+	}
+	applied := make(map[string]bool)
+	for id, stateResource := range state.Instances {
+		// Child modules live in other directories, so only the root module's resources are paired.
+		// The others still claim their instances, which are not unmanaged, but are not compared.
+		block, ok := blocks[stateResource.Name]
+		if stateResource.Module != "" || !ok {
+			if stateResource.Module != "" {
+				p.logger.Info("Resource in child module is not compared", "address", stateResource.Address())
+			} else {
+				p.logger.Info("Resource in state is no longer in configuration", "address", stateResource.Address())
+			}
+			stateResource.Unknown = []string{AllAttributes}
+			configSet.Instances[id] = stateResource
+			continue
+		}
+		applied[stateResource.Name] = true
+
+		r := InstanceResource{
+			Type:     stateResource.Type,
+			Name:     stateResource.Name,
+			IndexKey: stateResource.IndexKey,
+			Source:   stateResource.Source,
+			Config:   block.config,
+			Unknown:  block.unknown,
+		}
+		r.Config.InstanceID = id
+		r.Config.Tags, r.Unknown = defaults.apply(block)
+		r.Config.EBSBlockDevices = append([]entities.EBSBlockDevice(nil), block.config.EBSBlockDevices...)
+		// The state records the root device under its real name
+		if block.rootDeviceUnnamed {
+			for _, device := range stateResource.Config.EBSBlockDevices {
+				if device.Root {
+					r.Config.EBSBlockDevices[0].DeviceName = device.DeviceName
+				}
+			}
+		}
+		configSet.Instances[id] = r
+		configSet.add(r)
+	}
+
+	var names []string
+	for name := range blocks {
+		if !applied[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p.logger.Info("Resource in configuration has no instance in state yet", "address", "aws_instance."+name)
+	}
+
+	p.logger.Info("Returning parsed config set", "aws_instances", len(configSet.Instances))
+	return configSet, nil
+}
+
+// configFiles returns the .tf files to read for path and the state that pairs them with instance IDs.
+func (p *HCLConfigParser) configFiles(path string) ([]string, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", err
+	}
+	dir := path
+	files := []string{path}
+	if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.tf"))
+		if err != nil {
+			return nil, "", err
+		}
+		if len(files) == 0 {
+			return nil, "", fmt.Errorf("no .tf files in %s", path)
+		}
+	} else {
+		dir = filepath.Dir(path)
+	}
+
+	statePath := p.statePath
+	if statePath == "" {
+		statePath = filepath.Join(dir, stateFileName)
+	}
+	return files, statePath, nil
+}
+
+// defaultTags are the default_tags of the module's default aws provider, which the provider adds to
+// the tags of every resource.
+type defaultTags struct {
+	tags    map[string]string
+	unknown []string
+}
+
+// apply merges the default tags under the tags of block, whose own tags win, and returns them with
+// the block's unknown attributes.
+func (d defaultTags) apply(block hclInstance) (map[string]string, []string) {
+	tags := make(map[string]string, len(d.tags)+len(block.config.Tags))
+	for k, v := range d.tags {
+		tags[k] = v
+	}
+	for k, v := range block.config.Tags {
+		tags[k] = v
+	}
+	if len(d.unknown) == 0 {
+		return tags, block.unknown
+	}
+
+	unknown := append([]string(nil), block.unknown...)
+	for _, path := range d.unknown {
+		// A key the resource sets itself does not depend on the default
+		if key, ok := strings.CutPrefix(path, "tags."); ok {
+			if _, set := block.config.Tags[key]; set {
+				continue
+			}
+		}
+		if !contains(unknown, path) {
+			unknown = append(unknown, path)
+		}
+	}
+	sort.Strings(unknown)
+	return tags, unknown
+}
+
+// parseHCLFile adds the aws_instance resource blocks of one .tf file to blocks, and the default_tags
+// of its default aws provider to defaults.
+func parseHCLFile(path string, blocks map[string]hclInstance, defaults *defaultTags) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	file, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return fmt.Errorf("failed to parse HCL: %w", diags)
+	}
+
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		if isDefaultAWSProvider(block) {
+			parseDefaultTags(block.Body, defaults)
+			continue
+		}
+		if block.Type != "resource" || len(block.Labels) != 2 || block.Labels[0] != "aws_instance" {
+			continue
+		}
+		name := block.Labels[1]
+		if _, ok := blocks[name]; ok {
+			return fmt.Errorf("failed to parse HCL: duplicate resource aws_instance.%s in %s", name, path)
+		}
+		blocks[name] = parseInstanceBlock(block.Body)
+	}
+	return nil
+}
+
+// isDefaultAWSProvider reports whether block configures the aws provider resources use unless
+// they select an aliased one.
+func isDefaultAWSProvider(block *hclsyntax.Block) bool {
+	if block.Type != "provider" || len(block.Labels) != 1 || block.Labels[0] != "aws" {
+		return false
+	}
+	_, aliased := block.Body.Attributes["alias"]
+	return !aliased
+}
+
+// parseDefaultTags reads the tags of a provider's default_tags block into defaults.
+func parseDefaultTags(body *hclsyntax.Body, defaults *defaultTags) {
+	for _, block := range body.Blocks {
+		if block.Type != "default_tags" {
+			continue
+		}
+		attr, ok := block.Body.Attributes["tags"]
+		if !ok {
+			continue
+		}
+		tags, unknownKeys, known := literalMap(attr.Expr)
+		if !known {
+			defaults.unknown = append(defaults.unknown, "tags")
+		}
+		if defaults.tags == nil {
+			defaults.tags = make(map[string]string)
+		}
+		for k, v := range tags {
+			defaults.tags[k] = v
+		}
+		for _, k := range unknownKeys {
+			defaults.unknown = append(defaults.unknown, "tags."+k)
+		}
+	}
+}
+
+// parseInstanceBlock reads the literal attributes of an aws_instance block. Anything that depends on
+// variables, other resources or functions is recorded as unknown.
+func parseInstanceBlock(body *hclsyntax.Body) hclInstance {
+	instance := hclInstance{
+		config: entities.InstanceConfig{Tags: make(map[string]string)},
+	}
+	config := &instance.config
+
+	for name, target := range map[string]*string{
+		"instance_type":        &config.InstanceType,
+		"ami":                  &config.AMI,
+		"availability_zone":    &config.AvailabilityZone,
+		"key_name":             &config.KeyName,
+		"subnet_id":            &config.SubnetID,
+		"iam_instance_profile": &config.IAMInstanceProfile,
+	} {
+		attr, ok := body.Attributes[name]
+		if !ok {
+			if contains(computedAttributes, name) {
+				instance.unknown = append(instance.unknown, name)
+			}
+			continue
+		}
+		value, known := literalString(attr.Expr)
+		if !known {
+			instance.unknown = append(instance.unknown, name)
+			continue
+		}
+		*target = value
+	}
+
+	// vpc_security_group_ids takes precedence, as when reading state
+	groupsSet := false
+	for _, name := range []string{"vpc_security_group_ids", "security_groups"} {
+		attr, ok := body.Attributes[name]
+		if !ok || groupsSet {
+			continue
+		}
+		groupsSet = true
+		groups, known := literalStrings(attr.Expr)
+		if !known {
+			instance.unknown = append(instance.unknown, name)
+			continue
+		}
+		config.SecurityGroupIDs = groups
+	}
+	if !groupsSet {
+		instance.unknown = append(instance.unknown, "vpc_security_group_ids", "security_groups")
+	}
+
+	if attr, ok := body.Attributes["tags"]; ok {
+		tags, unknownKeys, known := literalMap(attr.Expr)
+		if !known {
+			instance.unknown = append(instance.unknown, "tags")
+		}
+		for k, v := range tags {
+			config.Tags[k] = v
+		}
+		for _, k := range unknownKeys {
+			instance.unknown = append(instance.unknown, "tags."+k)
+		}
+	}
+
+	rootConfigured := false
+	ebsIndex := 0
+	for _, block := range body.Blocks {
+		switch {
+		case block.Type == "root_block_device":
+			rootConfigured = true
+			instance.rootDeviceUnnamed = true
+			device, unknown := parseBlockDevice(block.Body)
+			device.Root = true
+			// The root device comes first, as in state
+			config.EBSBlockDevices = append([]entities.EBSBlockDevice{device}, config.EBSBlockDevices...)
+			for _, name := range unknown {
+				instance.unknown = append(instance.unknown, "root_block_device[0]."+name)
+			}
+		case block.Type == "ebs_block_device":
+			device, unknown := parseBlockDevice(block.Body)
+			ebsIndex++
+			config.EBSBlockDevices = append(config.EBSBlockDevices, device)
+			// Volumes are matched by device name, so without one no live volume can be told apart
+			if contains(unknown, "device_name") {
+				instance.unknown = append(instance.unknown, "ebs_block_device")
+				continue
+			}
+			for _, name := range unknown {
+				instance.unknown = append(instance.unknown, fmt.Sprintf("ebs_block_device[%q].%s", device.DeviceName, name))
+			}
+		case block.Type == "dynamic" && len(block.Labels) == 1:
+			// Generated blocks cannot be expanded without variables
+			instance.unknown = append(instance.unknown, block.Labels[0])
+			if block.Labels[0] == "root_block_device" {
+				rootConfigured = true
+			}
+		}
+	}
+	if !rootConfigured {
+		instance.unknown = append(instance.unknown, "root_block_device")
+	}
+	// Every instance has a root device. Left out, or generated, it is unknown rather than absent,
+	// so that the live one is not taken for an additional volume.
+	if len(config.EBSBlockDevices) == 0 || !config.EBSBlockDevices[0].Root {
+		instance.rootDeviceUnnamed = true
+		config.EBSBlockDevices = append([]entities.EBSBlockDevice{{Root: true}}, config.EBSBlockDevices...)
+	}
+	// Without ebs_block_device blocks, volumes attached separately are not this configuration's concern
+	if ebsIndex == 0 && !contains(instance.unknown, "ebs_block_device") {
+		instance.unknown = append(instance.unknown, "ebs_block_device")
+	}
+
+	sort.Strings(instance.unknown)
+	return instance
+}
+
+// parseBlockDevice reads a root_block_device or ebs_block_device block, returning the names of the
+// attributes it leaves unknown.
+func parseBlockDevice(body *hclsyntax.Body) (entities.EBSBlockDevice, []string) {
+	var device entities.EBSBlockDevice
+	var unknown []string
+	if attr, ok := body.Attributes["device_name"]; ok {
+		if name, known := literalString(attr.Expr); known {
+			device.DeviceName = name
+		} else {
+			unknown = append(unknown, "device_name")
+		}
+	}
+	// Every other attribute is computed by AWS when left out
+	for name, target := range map[string]*string{"volume_type": &device.VolumeType, "kms_key_id": &device.KMSKeyID} {
+		if value, known := literalAttribute(body, name, literalString); known {
+			*target = value
+		} else {
+			unknown = append(unknown, name)
+		}
+	}
+	for name, target := range map[string]*int{"volume_size": &device.VolumeSize, "iops": &device.Iops, "throughput": &device.Throughput} {
+		if value, known := literalAttribute(body, name, literalInt); known {
+			*target = value
+		} else {
+			unknown = append(unknown, name)
+		}
+	}
+	if encrypted, known := literalAttribute(body, "encrypted", literalBool); known {
+		device.Encrypted = encrypted
+	} else {
+		unknown = append(unknown, "encrypted")
+	}
+	sort.Strings(unknown)
+	return device, unknown
+}
+
+// literalAttribute reads the attribute name of body with literal, reporting it unknown when unset.
+func literalAttribute[T any](body *hclsyntax.Body, name string, literal func(hcl.Expression) (T, bool)) (T, bool) {
+	attr, ok := body.Attributes[name]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	return literal(attr.Expr)
+}
+
+// literalValue evaluates expr with no variables or functions in scope, so any reference makes it unknown.
+func literalValue(expr hcl.Expression) (cty.Value, bool) {
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() || !value.IsWhollyKnown() {
+		return cty.NilVal, false
+	}
+	return value, true
+}
+
+func literalString(expr hcl.Expression) (string, bool) {
+	value, ok := literalValue(expr)
+	if !ok {
+		return "", false
+	}
+	return ctyString(value)
+}
+
+func literalInt(expr hcl.Expression) (int, bool) {
+	value, ok := literalValue(expr)
+	if !ok {
+		return 0, false
+	}
+	number, err := convert.Convert(value, cty.Number)
+	if err != nil || number.IsNull() {
+		return 0, false
+	}
+	n, _ := number.AsBigFloat().Int64()
+	return int(n), true
+}
+
+func literalBool(expr hcl.Expression) (bool, bool) {
+	value, ok := literalValue(expr)
+	if !ok {
+		return false, false
+	}
+	b, err := convert.Convert(value, cty.Bool)
+	if err != nil || b.IsNull() {
+		return false, false
+	}
+	return b.True(), true
+}
+
+func literalStrings(expr hcl.Expression) ([]string, bool) {
+	value, ok := literalValue(expr)
+	if !ok || value.IsNull() || !value.CanIterateElements() {
+		return nil, false
+	}
+	var result []string
+	for it := value.ElementIterator(); it.Next(); {
+		_, element := it.Element()
+		s, ok := ctyString(element)
+		if !ok {
+			return nil, false
+		}
+		result = append(result, s)
+	}
+	return result, true
+}
+
+// literalMap reads a map of strings. An object constructor with some non-literal values still
+// yields its literal entries, and the keys of the others.
+func literalMap(expr hclsyntax.Expression) (map[string]string, []string, bool) {
+	if value, ok := literalValue(expr); ok && !value.IsNull() && value.CanIterateElements() {
+		result := make(map[string]string)
+		for it := value.ElementIterator(); it.Next(); {
+			key, element := it.Element()
+			s, ok := ctyString(element)
+			if !ok {
+				return nil, nil, false
+			}
+			result[key.AsString()] = s
+		}
+		return result, nil, true
+	}
+
+	object, ok := expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		return nil, nil, false
+	}
+	result := make(map[string]string)
+	var unknownKeys []string
+	for _, item := range object.Items {
+		key, ok := literalString(item.KeyExpr)
+		if !ok {
+			return nil, nil, false
+		}
+		if value, ok := literalString(item.ValueExpr); ok {
+			result[key] = value
+		} else {
+			unknownKeys = append(unknownKeys, key)
+		}
+	}
+	return result, unknownKeys, true
+}
+
+func ctyString(value cty.Value) (string, bool) {
+	if value.IsNull() {
+		return "", true
+	}
+	s, err := convert.Convert(value, cty.String)
+	if err != nil {
+		return "", false
+	}
+	return s.AsString(), true
+}
+
+// AllAttributes in Unknown marks every attribute of a resource as unknown, for resources whose
+// configuration is not read.
+const AllAttributes = "*"
+
+// IsUnknown reports whether the desired value of the attribute at path is unknown, directly or
+// through an enclosing attribute (tags covers tags.Owner). An unknown nested attribute leaves the
+// rest of its block known: a volume whose iops is unknown is still compared on its size.
+func (r InstanceResource) IsUnknown(path string) bool {
+	if contains(r.Unknown, AllAttributes) {
+		return true
+	}
+	for _, unknown := range r.Unknown {
+		if pathContains(unknown, path) {
+			return true
+		}
+	}
+	return false
+}
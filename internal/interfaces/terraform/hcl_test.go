@@ -0,0 +1,228 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+)
+
+const hclConfig = `
+variable "instance_type" {}
+
+resource "aws_instance" "web" {
+  count                  = 2
+  ami                    = "ami-0abcdef"
+  instance_type          = "t3.micro"
+  subnet_id              = "subnet-1"
+  key_name               = "ops"
+  vpc_security_group_ids = ["sg-1", "sg-2"]
+
+  tags = {
+    Name        = "web"
+    Environment = "prod"
+    Owner       = var.owner
+  }
+
+  root_block_device {
+    volume_size = 20
+    volume_type = "gp3"
+    iops        = 3000
+    throughput  = 125
+    encrypted   = true
+    kms_key_id  = "alias/ebs"
+  }
+
+  ebs_block_device {
+    device_name = "/dev/sdb"
+    volume_size = 100
+    encrypted   = false
+  }
+}
+
+resource "aws_instance" "worker" {
+  ami                    = data.aws_ami.ubuntu.id
+  instance_type          = var.instance_type
+  vpc_security_group_ids = [aws_security_group.worker.id]
+  tags                   = merge(local.tags, { Name = "worker" })
+}
+
+resource "aws_instance" "planned" {
+  ami           = "ami-0abcdef"
+  instance_type = "t3.nano"
+}
+
+resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+}
+`
+
+const hclState = `{
+	"version": 4, "serial": 3, "lineage": "l-hcl",
+	"resources": [
+		{"mode": "managed", "type": "aws_instance", "name": "web", "instances": [
+			{"index_key": 0, "attributes": {"id": "i-web0", "instance_type": "t2.micro", "root_block_device": [{"device_name": "/dev/xvda", "volume_size": 8}]}},
+			{"index_key": 1, "attributes": {"id": "i-web1", "instance_type": "t2.micro"}}
+		]},
+		{"mode": "managed", "type": "aws_instance", "name": "worker", "instances": [{"attributes": {"id": "i-worker"}}]},
+		{"mode": "managed", "type": "aws_instance", "name": "removed", "instances": [{"attributes": {"id": "i-removed"}}]},
+		{"module": "module.db", "mode": "managed", "type": "aws_instance", "name": "web", "instances": [{"attributes": {"id": "i-db"}}]}
+	]
+}`
+
+func writeHCLModule(t *testing.T) string {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(hclConfig), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "terraform.tfstate"), []byte(hclState), 0o644))
+	return dir
+}
+
+func TestHCLConfigParser_ParseTFState(t *testing.T) {
+	dir := writeHCLModule(t)
+	mockLog := &mockLogger{}
+	parser := NewHCLConfigParser(mockLog, NewTFStateParser(&mockLogger{}), "")
+
+	configSet, err := parser.ParseTFState(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, FormatHCL, configSet.Format)
+	assert.Equal(t, []StateVersion{{Source: filepath.Join(dir, "terraform.tfstate"), Serial: 3, Lineage: "l-hcl"}}, configSet.States)
+
+	// Only root module resources declared in the configuration are paired; the others still claim
+	// their instances, with nothing to compare
+	assert.Len(t, configSet.Instances, 5)
+	for _, id := range []string{"i-removed", "i-db"} {
+		assert.Equal(t, []string{AllAttributes}, configSet.Instances[id].Unknown)
+		assert.True(t, configSet.Instances[id].IsUnknown("instance_type"))
+	}
+	assert.Equal(t, "module.db.aws_instance.web", configSet.Instances["i-db"].Address())
+
+	web0 := configSet.Instances["i-web0"]
+	assert.Equal(t, "aws_instance.web[0]", web0.Address())
+	assert.Equal(t, entities.InstanceConfig{
+		InstanceID:       "i-web0",
+		InstanceType:     "t3.micro",
+		AMI:              "ami-0abcdef",
+		KeyName:          "ops",
+		SubnetID:         "subnet-1",
+		SecurityGroupIDs: []string{"sg-1", "sg-2"},
+		Tags:             map[string]string{"Name": "web", "Environment": "prod"},
+		EBSBlockDevices: []entities.EBSBlockDevice{
+			{DeviceName: "/dev/xvda", VolumeSize: 20, VolumeType: "gp3", Iops: 3000, Throughput: 125, Encrypted: true, KMSKeyID: "alias/ebs", Root: true},
+			{DeviceName: "/dev/sdb", VolumeSize: 100},
+		},
+	}, web0.Config)
+	assert.Equal(t, []string{
+		"availability_zone",
+		`ebs_block_device["/dev/sdb"].iops`,
+		`ebs_block_device["/dev/sdb"].kms_key_id`,
+		`ebs_block_device["/dev/sdb"].throughput`,
+		`ebs_block_device["/dev/sdb"].volume_type`,
+		"tags.Owner",
+	}, web0.Unknown)
+	// Only the attributes left out are unknown, not the volume or its other attributes
+	assert.True(t, web0.IsUnknown(`ebs_block_device["/dev/sdb"].iops`))
+	assert.False(t, web0.IsUnknown(`ebs_block_device["/dev/sdb"].volume_size`))
+	assert.False(t, web0.IsUnknown(`ebs_block_device["/dev/sdb"]`))
+	assert.False(t, web0.IsUnknown(`ebs_block_device["/dev/sdz"]`))
+
+	// The state has no root device for web[1], so its name stays unknown to the comparison
+	web1 := configSet.Instances["i-web1"]
+	assert.Equal(t, "t3.micro", web1.Config.InstanceType)
+	assert.Equal(t, "", web1.Config.EBSBlockDevices[0].DeviceName)
+
+	worker := configSet.Instances["i-worker"]
+	assert.True(t, worker.IsUnknown("instance_type"))
+	assert.True(t, worker.IsUnknown("ami"))
+	assert.True(t, worker.IsUnknown("vpc_security_group_ids"))
+	assert.True(t, worker.IsUnknown("tags.Name"))
+	assert.True(t, worker.IsUnknown("root_block_device"))
+	assert.True(t, worker.IsUnknown("ebs_block_device"))
+	assert.True(t, worker.IsUnknown("root_block_device[0].volume_size"))
+	assert.False(t, worker.IsUnknown("key_name"))
+	// The root device left out of the block is unknown, so it is not compared as an additional volume
+	assert.Equal(t, []entities.EBSBlockDevice{{Root: true}}, worker.Config.EBSBlockDevices)
+
+	assert.Contains(t, mockLog.logs, "Resource in state is no longer in configuration")
+	assert.Contains(t, mockLog.logs, "Resource in child module is not compared")
+	assert.Contains(t, mockLog.logs, "Resource in configuration has no instance in state yet")
+}
+
+func TestHCLConfigParser_DefaultTags(t *testing.T) {
+	dir := writeHCLModule(t)
+	providers := `
+provider "aws" {
+  region = "us-east-1"
+
+  default_tags {
+    tags = {
+      Environment = "staging"
+      Team        = "platform"
+      CostCenter  = var.cost_center
+    }
+  }
+}
+
+provider "aws" {
+  alias = "replica"
+
+  default_tags {
+    tags = { Replica = "true" }
+  }
+}
+`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "providers.tf"), []byte(providers), 0o644))
+
+	configSet, err := NewHCLConfigParser(&mockLogger{}, NewTFStateParser(&mockLogger{}), "").ParseTFState(dir)
+	assert.NoError(t, err)
+
+	// The resource's own tags win over the defaults, and aliased providers do not apply
+	web0 := configSet.Instances["i-web0"]
+	assert.Equal(t, map[string]string{"Name": "web", "Environment": "prod", "Team": "platform"}, web0.Config.Tags)
+	assert.True(t, web0.IsUnknown("tags.CostCenter"))
+	assert.True(t, web0.IsUnknown("tags.Owner"))
+	assert.False(t, web0.IsUnknown("tags.Environment"))
+
+	t.Run("NonLiteralDefaults", func(t *testing.T) {
+		dir := writeHCLModule(t)
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "providers.tf"), []byte(`
+provider "aws" {
+  default_tags {
+    tags = local.default_tags
+  }
+}
+`), 0o644))
+		mockLog := &mockLogger{}
+
+		configSet, err := NewHCLConfigParser(mockLog, NewTFStateParser(&mockLogger{}), "").ParseTFState(dir)
+		assert.NoError(t, err)
+		assert.True(t, configSet.Instances["i-web0"].IsUnknown("tags.Team"))
+		assert.Contains(t, mockLog.logs, "Provider default_tags are not literal, tags will not be compared")
+	})
+}
+
+func TestHCLConfigParser_Errors(t *testing.T) {
+	t.Run("InvalidHCL", func(t *testing.T) {
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`resource "aws_instance" "web" {`), 0o644))
+
+		_, err := NewHCLConfigParser(&mockLogger{}, NewTFStateParser(&mockLogger{}), "").ParseTFState(dir)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse HCL")
+	})
+
+	t.Run("NoConfiguration", func(t *testing.T) {
+		_, err := NewHCLConfigParser(&mockLogger{}, NewTFStateParser(&mockLogger{}), "").ParseTFState(t.TempDir())
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "no .tf files")
+	})
+
+	t.Run("MissingState", func(t *testing.T) {
+		dir := writeHCLModule(t)
+		_, err := NewHCLConfigParser(&mockLogger{}, NewTFStateParser(&mockLogger{}), filepath.Join(dir, "other.tfstate")).ParseTFState(dir)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to read state for instance IDs")
+	})
+}
@@ -0,0 +1,183 @@
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+	"github.com/cstudio7/drift-detector/internal/interfaces/logger"
+)
+
+// LockInfo is the lock metadata Terraform records while it holds a state lock.
+type LockInfo struct {
+	ID        string `json:"ID"`
+	Operation string `json:"Operation"`
+	Info      string `json:"Info"`
+	Who       string `json:"Who"`
+	Version   string `json:"Version"`
+	Created   string `json:"Created"`
+	Path      string `json:"Path"`
+}
+
+// StateLockedError is returned when a state is locked by another Terraform operation.
+type StateLockedError struct {
+	Source string
+	Lock   LockInfo
+}
+
+func (e *StateLockedError) Error() string {
+	if e.Lock.ID == "" {
+		return fmt.Sprintf("%s: %s", entities.ErrStateLocked, e.Source)
+	}
+	return fmt.Sprintf("%s: %s (lock %s, %s by %s since %s)", entities.ErrStateLocked, e.Source, e.Lock.ID, e.Lock.Operation, e.Lock.Who, e.Lock.Created)
+}
+
+// Unwrap allows errors.Is(err, entities.ErrStateLocked).
+func (e *StateLockedError) Unwrap() error {
+	return entities.ErrStateLocked
+}
+
+// LockInspector is implemented by StateSources that can report a lock held on their state
+// without taking it.
+type LockInspector interface {
+	// LockInfo returns the lock currently held on the state, or nil when it is not locked.
+	LockInfo(ctx context.Context) (*LockInfo, error)
+}
+
+// UnlockedOpener is implemented by StateSources that take the state lock while reading, so that
+// LockModeWarn can still read a state whose lock is held.
+type UnlockedOpener interface {
+	// OpenUnlocked opens the state without taking its lock.
+	OpenUnlocked(ctx context.Context) (io.ReadCloser, error)
+}
+
+// LockMode selects what the parser does when a state is locked by a running operation.
+type LockMode string
+
+const (
+	// LockModeWarn logs the lock, or a failure to check it, and reads the state anyway.
+	LockModeWarn LockMode = "warn"
+
+	// LockModeWait polls until the lock is released or the timeout passes.
+	LockModeWait LockMode = "wait"
+
+	// LockModeAbort fails with a StateLockedError, or with the error when the lock cannot be checked.
+	LockModeAbort LockMode = "abort"
+)
+
+// ParseLockMode validates a lock mode given on the command line.
+func ParseLockMode(mode string) (LockMode, error) {
+	switch LockMode(mode) {
+	case LockModeWarn, LockModeWait, LockModeAbort:
+		return LockMode(mode), nil
+	default:
+		return "", fmt.Errorf("%w: lock mode must be warn, wait or abort, got %q", entities.ErrInvalidArguments, mode)
+	}
+}
+
+// LockPolicy controls how a locked state is handled. The zero value does not check for locks.
+type LockPolicy struct {
+	Mode LockMode
+	// Timeout bounds how long LockModeWait waits for the lock to be released.
+	Timeout time.Duration
+	// PollInterval is how often LockModeWait checks the lock again.
+	PollInterval time.Duration
+}
+
+// defaultLockPollInterval is used when a LockPolicy sets none.
+const defaultLockPollInterval = 2 * time.Second
+
+// lockInfoPath returns where the local backend records the lock on a state file,
+// e.g. .terraform.tfstate.lock.info next to terraform.tfstate.
+func lockInfoPath(statePath string) string {
+	return filepath.Join(filepath.Dir(statePath), "."+filepath.Base(statePath)+".lock.info")
+}
+
+// LockInfo reads the local backend's lock info file next to the state.
+func (s FileSource) LockInfo(ctx context.Context) (*LockInfo, error) {
+	path := lockInfoPath(s.Path)
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lock := &LockInfo{Path: s.Path}
+	// The file's presence is the lock; its content only describes the holder
+	_ = json.Unmarshal(content, lock)
+	return lock, nil
+}
+
+// openWithLockPolicy opens source after applying policy: a lock reported by the source, or by
+// Open itself for sources that lock while reading, is logged, waited out or returned as an error.
+// A lock that cannot be checked only fails the read in LockModeAbort; otherwise it is logged and
+// the state read as if unlocked.
+func openWithLockPolicy(ctx context.Context, source StateSource, policy LockPolicy, log logger.Logger) (io.ReadCloser, error) {
+	if policy.Mode == "" {
+		return source.Open(ctx)
+	}
+	interval := policy.PollInterval
+	if interval <= 0 {
+		interval = defaultLockPollInterval
+	}
+	deadline := time.Now().Add(policy.Timeout)
+
+	for {
+		var lockedErr *StateLockedError
+		var err error
+		if inspector, ok := source.(LockInspector); ok {
+			var lock *LockInfo
+			lock, err = inspector.LockInfo(ctx)
+			if err != nil {
+				if policy.Mode == LockModeAbort {
+					return nil, fmt.Errorf("failed to check lock on %s: %w", source, err)
+				}
+				log.Warn("Failed to check state lock, drift may include an operation in progress", "file_path", source.String(), "error", err.Error())
+			}
+			if lock != nil {
+				lockedErr = &StateLockedError{Source: source.String(), Lock: *lock}
+			}
+		}
+
+		if lockedErr == nil || policy.Mode == LockModeWarn {
+			if lockedErr != nil {
+				warnLocked(log, lockedErr)
+			}
+			var reader io.ReadCloser
+			reader, err = source.Open(ctx)
+			if !errors.As(err, &lockedErr) {
+				return reader, err
+			}
+			// The source locks while reading and found the lock held
+			if opener, ok := source.(UnlockedOpener); ok && policy.Mode == LockModeWarn {
+				warnLocked(log, lockedErr)
+				return opener.OpenUnlocked(ctx)
+			}
+		}
+
+		if policy.Mode != LockModeWait {
+			return nil, lockedErr
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out after %s waiting for lock: %w", policy.Timeout, lockedErr)
+		}
+		log.Info("Waiting for state lock to be released", "file_path", source.String(), "lock_id", lockedErr.Lock.ID, "who", lockedErr.Lock.Who)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func warnLocked(log logger.Logger, lockedErr *StateLockedError) {
+	log.Warn("State is locked, drift may include an operation in progress", "file_path", lockedErr.Source, "lock_id", lockedErr.Lock.ID, "operation", lockedErr.Lock.Operation, "who", lockedErr.Lock.Who, "created", lockedErr.Lock.Created)
+}
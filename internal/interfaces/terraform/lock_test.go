@@ -0,0 +1,175 @@
+package terraform
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+)
+
+const lockedByApply = `{"ID": "f2a1", "Operation": "OperationTypeApply", "Who": "ci@runner", "Version": "1.7.5", "Created": "2026-10-01T12:00:00Z"}`
+
+// writeLockedState writes a v4 state with a local backend lock info file beside it.
+func writeLockedState(t *testing.T) (statePath, lockPath string) {
+	dir := t.TempDir()
+	statePath = filepath.Join(dir, "terraform.tfstate")
+	assert.NoError(t, os.WriteFile(statePath, []byte(`{"version": 4, "serial": 7, "lineage": "l-1", "resources": [
+		{"mode": "managed", "type": "aws_instance", "name": "web", "instances": [{"attributes": {"id": "i-web"}}]}
+	]}`), 0o644))
+	lockPath = filepath.Join(dir, ".terraform.tfstate.lock.info")
+	assert.NoError(t, os.WriteFile(lockPath, []byte(lockedByApply), 0o644))
+	return statePath, lockPath
+}
+
+func TestFileSource_LockInfo(t *testing.T) {
+	statePath, lockPath := writeLockedState(t)
+
+	lock, err := FileSource{Path: statePath}.LockInfo(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "f2a1", lock.ID)
+	assert.Equal(t, "OperationTypeApply", lock.Operation)
+
+	assert.NoError(t, os.Remove(lockPath))
+	lock, err = FileSource{Path: statePath}.LockInfo(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, lock)
+}
+
+func TestParseTFState_LockPolicy(t *testing.T) {
+	t.Run("NoPolicyIgnoresLock", func(t *testing.T) {
+		statePath, _ := writeLockedState(t)
+		_, err := NewTFStateParser(&mockLogger{}).ParseTFState(statePath)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Warn", func(t *testing.T) {
+		statePath, _ := writeLockedState(t)
+		mockLog := &mockLogger{}
+
+		configSet, err := NewTFStateParser(mockLog).WithLockPolicy(LockPolicy{Mode: LockModeWarn}).ParseTFState(statePath)
+		assert.NoError(t, err)
+		assert.Contains(t, configSet.Instances, "i-web")
+		assert.Equal(t, []StateVersion{{Source: statePath, Serial: 7, Lineage: "l-1"}}, configSet.States)
+		assert.Contains(t, mockLog.logs, "State is locked, drift may include an operation in progress")
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		statePath, _ := writeLockedState(t)
+
+		_, err := NewTFStateParser(&mockLogger{}).WithLockPolicy(LockPolicy{Mode: LockModeAbort}).ParseTFState(statePath)
+		assert.ErrorIs(t, err, entities.ErrStateLocked)
+		assert.Contains(t, err.Error(), "OperationTypeApply by ci@runner")
+	})
+
+	t.Run("WaitUntilReleased", func(t *testing.T) {
+		statePath, lockPath := writeLockedState(t)
+		time.AfterFunc(30*time.Millisecond, func() { os.Remove(lockPath) })
+
+		policy := LockPolicy{Mode: LockModeWait, Timeout: 5 * time.Second, PollInterval: 10 * time.Millisecond}
+		configSet, err := NewTFStateParser(&mockLogger{}).WithLockPolicy(policy).ParseTFState(statePath)
+		assert.NoError(t, err)
+		assert.Contains(t, configSet.Instances, "i-web")
+	})
+
+	t.Run("WaitTimesOut", func(t *testing.T) {
+		statePath, _ := writeLockedState(t)
+
+		policy := LockPolicy{Mode: LockModeWait, Timeout: 30 * time.Millisecond, PollInterval: 10 * time.Millisecond}
+		_, err := NewTFStateParser(&mockLogger{}).WithLockPolicy(policy).ParseTFState(statePath)
+		assert.ErrorIs(t, err, entities.ErrStateLocked)
+		assert.Contains(t, err.Error(), "timed out")
+	})
+}
+
+func TestS3Source_LockInfo(t *testing.T) {
+	objects := map[string]string{
+		"/my-states/app/terraform.tfstate.tflock": lockedByApply,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `<Error><Code>NoSuchKey</Code><Message>not found</Message></Error>`)
+			return
+		}
+		_, _ = io.WriteString(w, body)
+	}))
+	defer server.Close()
+
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  aws.AnonymousCredentials{},
+	})
+
+	lock, err := NewS3Source(client, S3Location{Bucket: "my-states", Key: "app/terraform.tfstate"}).LockInfo(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "f2a1", lock.ID)
+
+	lock, err = NewS3Source(client, S3Location{Bucket: "my-states", Key: "app/terraform.tfstate", Workspace: "staging"}).LockInfo(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, lock)
+}
+
+// TestS3Source_LockStatusUnknown reads a state whose lock file S3 refuses to show, as it does for
+// missing objects when s3:ListBucket is not granted.
+func TestS3Source_LockStatusUnknown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/my-states/app/terraform.tfstate" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"version": 4, "resources": [
+			{"mode": "managed", "type": "aws_instance", "name": "web", "instances": [{"attributes": {"id": "i-web"}}]}
+		]}`)
+	}))
+	defer server.Close()
+
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  aws.AnonymousCredentials{},
+	})
+	sources := NewStateSources()
+	sources.Register("s3", S3SourceFactory(client))
+
+	lock, err := NewS3Source(client, S3Location{Bucket: "my-states", Key: "app/terraform.tfstate"}).LockInfo(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "lock status unknown")
+	assert.Nil(t, lock)
+
+	// Warn and wait log the failed check and read the state
+	for _, mode := range []LockMode{LockModeWarn, LockModeWait} {
+		mockLog := &mockLogger{}
+		configSet, err := NewTFStateParser(mockLog).WithSources(sources).WithLockPolicy(LockPolicy{Mode: mode}).ParseTFState("s3://my-states/app/terraform.tfstate")
+		assert.NoError(t, err)
+		assert.Contains(t, configSet.Instances, "i-web")
+		assert.Contains(t, mockLog.logs, "Failed to check state lock, drift may include an operation in progress")
+	}
+
+	_, err = NewTFStateParser(&mockLogger{}).WithSources(sources).WithLockPolicy(LockPolicy{Mode: LockModeAbort}).ParseTFState("s3://my-states/app/terraform.tfstate")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "lock status unknown")
+}
+
+func TestParseLockMode(t *testing.T) {
+	mode, err := ParseLockMode("wait")
+	assert.NoError(t, err)
+	assert.Equal(t, LockModeWait, mode)
+
+	_, err = ParseLockMode("ignore")
+	assert.ErrorIs(t, err, entities.ErrInvalidArguments)
+}
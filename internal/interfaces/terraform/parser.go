@@ -1,10 +1,10 @@
 package terraform
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"os"
 
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
 	"github.com/cstudio7/drift-detector/internal/interfaces/logger"
 )
 
@@ -15,17 +15,103 @@ type TFStateParser interface {
 
 // TFStateParserImpl is the implementation of TFStateParser.
 type TFStateParserImpl struct {
-	logger logger.Logger
+	logger           logger.Logger
+	sources          *StateSources
+	progress         ProgressFunc
+	progressInterval int64
+	lockPolicy       LockPolicy
 }
 
-// NewTFStateParser creates a new TFStateParserImpl.
+// NewTFStateParser creates a new TFStateParserImpl that reads local state files.
 func NewTFStateParser(logger logger.Logger) *TFStateParserImpl {
 	return &TFStateParserImpl{
-		logger: logger,
+		logger:           logger,
+		sources:          NewStateSources(),
+		progressInterval: defaultProgressInterval,
 	}
 }
 
+// WithSources makes the parser read state locations through sources, e.g. s3:// URLs.
+func (p *TFStateParserImpl) WithSources(sources *StateSources) *TFStateParserImpl {
+	p.sources = sources
+	return p
+}
+
+// WithLockPolicy makes the parser check for a lock on each state before reading it.
+func (p *TFStateParserImpl) WithLockPolicy(policy LockPolicy) *TFStateParserImpl {
+	p.lockPolicy = policy
+	return p
+}
+
+// WithProgress reports parsing progress to progress every interval bytes instead of logging it.
+func (p *TFStateParserImpl) WithProgress(interval int64, progress ProgressFunc) *TFStateParserImpl {
+	p.progressInterval = interval
+	p.progress = progress
+	return p
+}
+
+// StateFormat identifies the layout of a parsed Terraform state file.
+type StateFormat string
+
+const (
+	// FormatLegacy is the aggregated resources.aws_instance object of attribute lists.
+	FormatLegacy StateFormat = "legacy"
+
+	// FormatV3 is the pre-0.12 modules[].resources layout with flattened attributes.
+	FormatV3 StateFormat = "v3"
+
+	// FormatV4 is the Terraform v4 resources array with per-resource instances.
+	FormatV4 StateFormat = "v4"
+
+	// FormatOpenTofu is a v4 state written by OpenTofu, identified by its provider registry.
+	FormatOpenTofu StateFormat = "opentofu"
+
+	// FormatShowJSON is the values representation printed by `terraform show -json`.
+	FormatShowJSON StateFormat = "show-json"
+)
+
+// InstanceResource is the desired configuration of a single managed aws_instance.
+type InstanceResource struct {
+	Module   string                  `json:"module,omitempty"`
+	Type     string                  `json:"type"`
+	Name     string                  `json:"name"`
+	IndexKey interface{}             `json:"index_key,omitempty"`
+	Config   entities.InstanceConfig `json:"config"`
+	// Source is the state the resource was read from, set when several states are merged.
+	Source string `json:"source,omitempty"`
+	// Sensitive lists the attribute paths the state marks as sensitive, e.g. tags.Secret.
+	Sensitive []string `json:"sensitive,omitempty"`
+	// Unknown lists the attribute paths whose desired value is not known, e.g. a configuration
+	// attribute set from a variable; they are not compared.
+	Unknown []string `json:"unknown,omitempty"`
+}
+
+// Address returns the full Terraform resource address, e.g. module.app.aws_instance.web[0].
+func (r InstanceResource) Address() string {
+	address := r.Type + "." + r.Name
+	if r.Module != "" {
+		address = r.Module + "." + address
+	}
+	switch key := r.IndexKey.(type) {
+	case nil:
+	case string:
+		address += fmt.Sprintf("[%q]", key)
+	case float64:
+		address += fmt.Sprintf("[%d]", int(key))
+	default:
+		address += fmt.Sprintf("[%v]", key)
+	}
+	return address
+}
+
+// MatchesAddressPrefix reports whether the resource address starts with prefix at an address
+// step boundary, so module.app matches module.app.aws_instance.x and module.app["a"] but not module.application.
+func (r InstanceResource) MatchesAddressPrefix(prefix string) bool {
+	return pathContains(prefix, r.Address())
+}
+
 // InstanceConfigSet holds aggregated attributes for drift detection.
+// For v4 states it also holds one InstanceResource per instance, keyed by instance ID.
 type InstanceConfigSet struct {
 	InstanceTypes       []string `json:"instance_types"`
 	AMIs                []string `json:"amis"`
@@ -38,11 +124,28 @@ type InstanceConfigSet struct {
 	TagEnvironments     []string `json:"tag_environments"`
 	EBSVolumeSizes      []int    `json:"ebs_volume_sizes"`
 	EBSVolumeTypes      []string `json:"ebs_volume_types"`
+
+	// Format is the detected layout of the state the set was parsed from.
+	Format StateFormat `json:"-"`
+	// Instances is empty for legacy states, which carry no per-instance data.
+	Instances map[string]InstanceResource `json:"-"`
+	// States records the version of each state the set was parsed from.
+	States []StateVersion `json:"-"`
+}
+
+// StateVersion ties a parsed state to an exact revision: Terraform increments serial on every
+// write, and lineage is fixed when the state is first created.
+type StateVersion struct {
+	Source           string `json:"source"`
+	Serial           int    `json:"serial"`
+	Lineage          string `json:"lineage,omitempty"`
+	TerraformVersion string `json:"terraform_version,omitempty"`
 }
 
 // IsEmpty checks if an InstanceConfigSet is empty.
 func (c InstanceConfigSet) IsEmpty() bool {
-	return len(c.InstanceTypes) == 0 &&
+	return len(c.Instances) == 0 &&
+		len(c.InstanceTypes) == 0 &&
 		len(c.AMIs) == 0 &&
 		len(c.AvailabilityZones) == 0 &&
 		len(c.KeyNames) == 0 &&
@@ -55,7 +158,7 @@ func (c InstanceConfigSet) IsEmpty() bool {
 		len(c.EBSVolumeTypes) == 0
 }
 
-// TFState represents the structure of the simplified Terraform state file.
+// TFState represents the structure of the simplified (legacy) Terraform state file.
 type TFState struct {
 	Version          int    `json:"version"`
 	TerraformVersion string `json:"terraform_version"`
@@ -66,28 +169,81 @@ type TFState struct {
 	} `json:"resources"`
 }
 
+// UnsupportedStateVersionError is returned for state files whose version the parser cannot read.
+type UnsupportedStateVersionError struct {
+	Version int
+	// Detail explains the rejection when the version number alone does not.
+	Detail string
+}
+
+func (e *UnsupportedStateVersionError) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("%s %d: %s", entities.ErrUnsupportedStateVersion, e.Version, e.Detail)
+	}
+	return fmt.Sprintf("%s %d", entities.ErrUnsupportedStateVersion, e.Version)
+}
+
+// Unwrap allows errors.Is(err, entities.ErrUnsupportedStateVersion).
+func (e *UnsupportedStateVersionError) Unwrap() error {
+	return entities.ErrUnsupportedStateVersion
+}
+
+// ParseTFState reads a Terraform or OpenTofu state file, detecting its version and layout.
+// The state is streamed rather than read into memory, so very large states can be parsed.
 func (p *TFStateParserImpl) ParseTFState(filePath string) (InstanceConfigSet, error) {
-	// Log the file being parsed
-	p.logger.Info("Starting to parse file", "file_path", filePath)
+	// Log the file being parsed, without any credentials in its URL
+	location := RedactLocation(filePath)
+	p.logger.Info("Starting to parse file", "file_path", location)
 
-	// Read the content of the JSON state file
-	fileContent, err := os.ReadFile(filePath)
+	// Open the JSON state file, applying the lock policy first
+	source, err := p.sources.Resolve(filePath)
+	if err != nil {
+		p.logger.Error("Failed to read JSON state file", "file_path", location, "error", err.Error())
+		return InstanceConfigSet{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	stateReader, err := openWithLockPolicy(context.Background(), source, p.lockPolicy, p.logger)
 	if err != nil {
-		p.logger.Error("Failed to read JSON state file", "file_path", filePath, "error", err.Error())
+		p.logger.Error("Failed to read JSON state file", "file_path", location, "error", err.Error())
 		return InstanceConfigSet{}, fmt.Errorf("failed to read file: %w", err)
 	}
+	defer stateReader.Close()
 
-	// Parse JSON content
-	var state TFState
-	if err := json.Unmarshal(fileContent, &state); err != nil {
-		p.logger.Error("Failed to parse JSON state file", "file_path", filePath, "error", err.Error())
+	// Decode the state token by token, keeping only aws_instance resources
+	progress := p.progress
+	if progress == nil {
+		progress = p.logProgress
+	}
+	reader := newProgressReader(stateReader, location, p.progressInterval, progress)
+	header, configSet, err := decodeState(reader)
+	if reader.err != nil {
+		p.logger.Error("Failed to read JSON state file", "file_path", location, "error", reader.err.Error())
+		return InstanceConfigSet{}, fmt.Errorf("failed to read file: %w", reader.err)
+	}
+	if err != nil {
+		p.logger.Error("Failed to parse JSON state file", "file_path", location, "error", err.Error())
 		return InstanceConfigSet{}, fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
 	// Log parsed Terraform version
-	p.logger.Info("Parsed Terraform state", "version", state.Version, "terraform_version", state.TerraformVersion)
+	format, err := detectFormat(header)
+	if err != nil {
+		p.logger.Error("Unsupported Terraform state", "file_path", location, "error", err.Error())
+		return InstanceConfigSet{}, err
+	}
+	p.logger.Info("Parsed Terraform state", "version", header.Version, "terraform_version", header.TerraformVersion, "format", format, "serial", header.Serial, "lineage", header.Lineage)
 
-	configSet := state.Resources.AWSInstance
+	if format == FormatLegacy {
+		configSet.Instances = nil
+	} else {
+		p.logger.Info("Parsed aws_instance resources", "count", len(configSet.Instances))
+	}
+	configSet.Format = format
+	configSet.States = []StateVersion{{
+		Source:           location,
+		Serial:           header.Serial,
+		Lineage:          header.Lineage,
+		TerraformVersion: header.TerraformVersion,
+	}}
 
 	// Log aggregated attributes
 	p.logger.Info("Parsed instance types", "instance_types", configSet.InstanceTypes)
@@ -101,7 +257,38 @@ func (p *TFStateParserImpl) ParseTFState(filePath string) (InstanceConfigSet, er
 	p.logger.Info("Parsed tag environments", "tag_environments", configSet.TagEnvironments)
 	p.logger.Info("Parsed EBS volume sizes", "ebs_volume_sizes", configSet.EBSVolumeSizes)
 	p.logger.Info("Parsed EBS volume types", "ebs_volume_types", configSet.EBSVolumeTypes)
+	if sensitive := configSet.sensitiveCount(); sensitive > 0 {
+		p.logger.Info("Parsed sensitive attributes, values withheld from logs and reports", "count", sensitive)
+	}
 
 	p.logger.Info("Returning parsed config set")
 	return configSet, nil
 }
+
+// detectFormat picks the layout from the state version. Version 4 covers both the v4 resources
+// array and the legacy aggregated object; encrypted OpenTofu states carry no version at all.
+func detectFormat(header stateHeader) (StateFormat, error) {
+	switch {
+	case header.encrypted:
+		return "", &UnsupportedStateVersionError{Version: header.Version, Detail: "state is encrypted by OpenTofu, decrypt it with `tofu show -json` first"}
+	case header.Version == 3:
+		return FormatV3, nil
+	case header.Version == 4 && header.legacyLayout:
+		return FormatLegacy, nil
+	case header.Version == 4 && header.openTofu:
+		return FormatOpenTofu, nil
+	case header.Version == 4:
+		return FormatV4, nil
+	default:
+		return "", &UnsupportedStateVersionError{Version: header.Version}
+	}
+}
+
+// logProgress is the default ProgressFunc, logging how far through a large state the parser is.
+func (p *TFStateParserImpl) logProgress(location string, read, total int64) {
+	if total > 0 {
+		p.logger.Info("Parsing state file", "file_path", location, "bytes_read", read, "total_bytes", total, "percent", read*100/total)
+		return
+	}
+	p.logger.Info("Parsing state file", "file_path", location, "bytes_read", read)
+}
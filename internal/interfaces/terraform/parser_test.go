@@ -5,6 +5,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
 	_ "github.com/cstudio7/drift-detector/internal/interfaces/logger" // Blank identifier to suppress unused import warning
 	"github.com/stretchr/testify/assert"
 )
@@ -90,7 +91,10 @@ func TestParseTFState(t *testing.T) {
 		assert.NoError(t, err)
 
 		// Verify the parsed config set
-		assert.Equal(t, validState.Resources.AWSInstance, configSet)
+		expected := validState.Resources.AWSInstance
+		expected.Format = FormatLegacy
+		expected.States = []StateVersion{{Source: tempFile.Name(), Serial: 1, Lineage: "abc123", TerraformVersion: "1.5.0"}}
+		assert.Equal(t, expected, configSet)
 
 		// Verify logging
 		expectedLogs := []string{
@@ -202,7 +206,10 @@ func TestParseTFState(t *testing.T) {
 		assert.NoError(t, err)
 
 		// Verify the parsed config set
-		assert.Equal(t, emptyState.Resources.AWSInstance, configSet)
+		expected := emptyState.Resources.AWSInstance
+		expected.Format = FormatLegacy
+		expected.States = []StateVersion{{Source: tempFile.Name(), Serial: 1, Lineage: "abc123", TerraformVersion: "1.5.0"}}
+		assert.Equal(t, expected, configSet)
 		assert.Empty(t, configSet.InstanceTypes)
 		assert.Empty(t, configSet.AMIs)
 		assert.Empty(t, configSet.AvailabilityZones)
@@ -236,4 +243,180 @@ func TestParseTFState(t *testing.T) {
 			assert.Contains(t, mockLog.logs, logMsg, "Expected log message: %s", logMsg)
 		}
 	})
+	// Test case 5: Terraform v4 state with per-resource instances
+	t.Run("V4StateFile", func(t *testing.T) {
+		// Reset mock logger
+		mockLog.logs = []string{}
+
+		configSet, err := parser.ParseTFState("../../../testdata/sample-tfstate.json")
+		assert.NoError(t, err)
+
+		assert.Equal(t, FormatV4, configSet.Format)
+		assert.False(t, configSet.IsEmpty())
+		assert.Len(t, configSet.Instances, 2)
+
+		resource, ok := configSet.Instances["i-06d8a793ad510fdea"]
+		assert.True(t, ok)
+		assert.Equal(t, "aws_instance", resource.Type)
+		assert.Equal(t, "example", resource.Name)
+		assert.Equal(t, "i-06d8a793ad510fdea", resource.Config.InstanceID)
+		assert.Equal(t, "t2.micro", resource.Config.InstanceType)
+		assert.Equal(t, "subnet-0fd29464681088be4", resource.Config.SubnetID)
+		assert.Equal(t, []string{"sg-0a1b2c3d4e5f6g7h8"}, resource.Config.SecurityGroupIDs)
+		assert.Equal(t, "test-instance", resource.Config.Tags["Name"])
+
+		// Aggregated lists are derived from the instances
+		assert.Equal(t, []string{"t2.micro"}, configSet.InstanceTypes)
+		assert.ElementsMatch(t, []string{"test-instance", "test-value"}, configSet.TagNames)
+
+		assert.Contains(t, mockLog.logs, "Parsed aws_instance resources")
+	})
+
+	// Test case 6: v4 state skips data sources and other resource types
+	t.Run("V4StateSkipsOtherResources", func(t *testing.T) {
+		tempFile, err := os.CreateTemp("", "v4_tfstate_*.json")
+		assert.NoError(t, err)
+		defer os.Remove(tempFile.Name())
+
+		_, err = tempFile.WriteString(`{
+			"version": 4,
+			"resources": [
+				{"mode": "data", "type": "aws_instance", "name": "lookup", "instances": [{"attributes": {"id": "i-data"}}]},
+				{"mode": "managed", "type": "aws_s3_bucket", "name": "logs", "instances": [{"attributes": {"id": "logs"}}]},
+				{"mode": "managed", "type": "aws_instance", "name": "web", "instances": [
+					{"index_key": 0, "attributes": {"id": "i-web0", "instance_type": "t3.micro", "vpc_security_group_ids": ["sg-1"], "security_groups": ["default"],
+						"tags": {"Name": "web-0"}, "tags_all": {"Name": "web-0", "Team": "platform"},
+						"root_block_device": [{"device_name": "/dev/xvda", "volume_size": 8, "volume_type": "gp3", "iops": 3000, "throughput": 125, "encrypted": true, "kms_key_id": "arn:aws:kms:us-east-1:123456789012:key/abc", "volume_id": "vol-root"}],
+						"ebs_block_device": [{"device_name": "/dev/sdf", "volume_size": 100, "volume_type": "gp2", "iops": 300, "encrypted": false, "volume_id": "vol-data"}]}}
+				]}
+			]
+		}`)
+		assert.NoError(t, err)
+		tempFile.Close()
+
+		configSet, err := parser.ParseTFState(tempFile.Name())
+		assert.NoError(t, err)
+		assert.Len(t, configSet.Instances, 1)
+
+		resource := configSet.Instances["i-web0"]
+		assert.Equal(t, float64(0), resource.IndexKey)
+		assert.Equal(t, []string{"sg-1"}, resource.Config.SecurityGroupIDs)
+		// tags_all includes the provider's default_tags
+		assert.Equal(t, map[string]string{"Name": "web-0", "Team": "platform"}, resource.Config.Tags)
+		assert.Equal(t, []entities.EBSBlockDevice{
+			{DeviceName: "/dev/xvda", VolumeSize: 8, VolumeType: "gp3", Iops: 3000, Throughput: 125, Encrypted: true, KMSKeyID: "arn:aws:kms:us-east-1:123456789012:key/abc", VolumeID: "vol-root", Root: true},
+			{DeviceName: "/dev/sdf", VolumeSize: 100, VolumeType: "gp2", Iops: 300, VolumeID: "vol-data"},
+		}, resource.Config.EBSBlockDevices)
+		assert.Equal(t, []int{8, 100}, configSet.EBSVolumeSizes)
+	})
+
+	// Test case 7: v4 state attaches aws_network_interface resources to their instances
+	t.Run("V4StateAttachesNetworkInterfaces", func(t *testing.T) {
+		tempFile, err := os.CreateTemp("", "v4_tfstate_*.json")
+		assert.NoError(t, err)
+		defer os.Remove(tempFile.Name())
+
+		_, err = tempFile.WriteString(`{
+			"version": 4,
+			"resources": [
+				{"mode": "managed", "type": "aws_network_interface", "name": "mgmt", "instances": [
+					{"attributes": {"id": "eni-mgmt", "security_groups": ["sg-mgmt"], "attachment": [{"instance": "i-web0", "device_index": 2}]}}
+				]},
+				{"mode": "managed", "type": "aws_network_interface", "name": "db", "instances": [
+					{"attributes": {"id": "eni-db", "security_groups": ["sg-db", "sg-mgmt"], "attachment": [{"instance": "i-web0", "device_index": "1"}]}}
+				]},
+				{"mode": "managed", "type": "aws_network_interface", "name": "primary", "instances": [
+					{"attributes": {"id": "eni-primary", "security_groups": ["sg-1"], "attachment": [{"instance": "i-web0", "device_index": 0}]}}
+				]},
+				{"mode": "managed", "type": "aws_instance", "name": "web", "instances": [
+					{"attributes": {"id": "i-web0", "instance_type": "t3.micro", "vpc_security_group_ids": ["sg-1"]}}
+				]}
+			]
+		}`)
+		assert.NoError(t, err)
+		tempFile.Close()
+
+		configSet, err := parser.ParseTFState(tempFile.Name())
+		assert.NoError(t, err)
+		assert.Len(t, configSet.Instances, 1)
+		// The primary interface's groups are the instance's, and the others are ordered by device index
+		assert.Equal(t, []entities.NetworkInterface{
+			{ID: "eni-db", DeviceIndex: 1, SecurityGroupIDs: []string{"sg-db", "sg-mgmt"}},
+			{ID: "eni-mgmt", DeviceIndex: 2, SecurityGroupIDs: []string{"sg-mgmt"}},
+		}, configSet.Instances["i-web0"].Config.NetworkInterfaces)
+	})
+}
+
+func TestInstanceResource_Address(t *testing.T) {
+	assert.Equal(t, "aws_instance.web", InstanceResource{Type: "aws_instance", Name: "web"}.Address())
+	assert.Equal(t, "aws_instance.web[0]", InstanceResource{Type: "aws_instance", Name: "web", IndexKey: float64(0)}.Address())
+	assert.Equal(t, `aws_instance.node["a"]`, InstanceResource{Type: "aws_instance", Name: "node", IndexKey: "a"}.Address())
+	assert.Equal(t, `module.app.module.asg.aws_instance.node["a"]`, InstanceResource{Module: "module.app.module.asg", Type: "aws_instance", Name: "node", IndexKey: "a"}.Address())
+	assert.Equal(t, `module.app["eu"].aws_instance.node[1]`, InstanceResource{Module: `module.app["eu"]`, Type: "aws_instance", Name: "node", IndexKey: float64(1)}.Address())
+}
+
+func TestInstanceResource_MatchesAddressPrefix(t *testing.T) {
+	r := InstanceResource{Module: `module.app["eu"].module.asg`, Type: "aws_instance", Name: "node", IndexKey: "a"}
+
+	assert.True(t, r.MatchesAddressPrefix("module.app"))
+	assert.True(t, r.MatchesAddressPrefix(`module.app["eu"]`))
+	assert.True(t, r.MatchesAddressPrefix(`module.app["eu"].module.asg.aws_instance.node`))
+	assert.True(t, r.MatchesAddressPrefix(r.Address()))
+	assert.False(t, r.MatchesAddressPrefix("module.ap"))
+	assert.False(t, r.MatchesAddressPrefix("module.asg"))
+	assert.False(t, r.MatchesAddressPrefix(`module.app["us"]`))
+}
+
+func TestParseTFState_ModuleAddresses(t *testing.T) {
+	tempFile, err := os.CreateTemp("", "module_tfstate_*.json")
+	assert.NoError(t, err)
+	defer os.Remove(tempFile.Name())
+
+	_, err = tempFile.WriteString(`{
+		"version": 4,
+		"resources": [
+			{"module": "module.app.module.asg", "mode": "managed", "type": "aws_instance", "name": "node", "instances": [
+				{"index_key": "a", "attributes": {"id": "i-a"}},
+				{"index_key": "b", "attributes": {"id": "i-b"}}
+			]},
+			{"mode": "managed", "type": "aws_instance", "name": "bastion", "instances": [{"attributes": {"id": "i-bastion"}}]}
+		]
+	}`)
+	assert.NoError(t, err)
+	tempFile.Close()
+
+	configSet, err := NewTFStateParser(&mockLogger{}).ParseTFState(tempFile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, `module.app.module.asg.aws_instance.node["a"]`, configSet.Instances["i-a"].Address())
+	assert.Equal(t, `module.app.module.asg.aws_instance.node["b"]`, configSet.Instances["i-b"].Address())
+	assert.Equal(t, "aws_instance.bastion", configSet.Instances["i-bastion"].Address())
+}
+
+func TestInstanceConfigSet_Merge(t *testing.T) {
+	a := InstanceConfigSet{
+		Format:        FormatV4,
+		InstanceTypes: []string{"t2.micro"},
+		Instances: map[string]InstanceResource{
+			"i-1": {Type: "aws_instance", Name: "a", Config: entities.InstanceConfig{InstanceID: "i-1"}},
+		},
+	}
+	b := InstanceConfigSet{
+		Format:         FormatV4,
+		InstanceTypes:  []string{"t2.micro", "m5.large"},
+		EBSVolumeSizes: []int{8},
+		Instances: map[string]InstanceResource{
+			"i-1": {Type: "aws_instance", Name: "b", Config: entities.InstanceConfig{InstanceID: "i-1"}},
+			"i-2": {Type: "aws_instance", Name: "c", Config: entities.InstanceConfig{InstanceID: "i-2"}},
+		},
+	}
+
+	var merged InstanceConfigSet
+	assert.Empty(t, merged.Merge(a))
+	assert.Equal(t, []string{"i-1"}, merged.Merge(b))
+
+	assert.Equal(t, FormatV4, merged.Format)
+	assert.Equal(t, "a", merged.Instances["i-1"].Name)
+	assert.Equal(t, "c", merged.Instances["i-2"].Name)
+	assert.Equal(t, []string{"t2.micro", "m5.large"}, merged.InstanceTypes)
+	assert.Equal(t, []int{8}, merged.EBSVolumeSizes)
 }
@@ -0,0 +1,127 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// sensitivePathStep is one step of a v4 sensitive_attributes path, e.g. {"type": "get_attr", "value": "tags"}.
+type sensitivePathStep struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// parseSensitiveAttributes converts the sensitive_attributes of a v4 instance into attribute paths
+// such as tags.Secret or root_block_device[0].kms_key_id.
+func parseSensitiveAttributes(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var paths [][]sensitivePathStep
+	if err := json.Unmarshal(raw, &paths); err != nil {
+		return nil, fmt.Errorf("failed to parse sensitive_attributes: %w", err)
+	}
+
+	var result []string
+	for _, steps := range paths {
+		var b strings.Builder
+		for _, step := range steps {
+			switch step.Type {
+			case "get_attr":
+				var name string
+				if err := json.Unmarshal(step.Value, &name); err != nil {
+					return nil, fmt.Errorf("failed to parse sensitive_attributes: %w", err)
+				}
+				appendPathKey(&b, name)
+			case "index":
+				var index struct {
+					Value interface{} `json:"value"`
+				}
+				if err := json.Unmarshal(step.Value, &index); err != nil {
+					return nil, fmt.Errorf("failed to parse sensitive_attributes: %w", err)
+				}
+				if n, ok := index.Value.(float64); ok {
+					fmt.Fprintf(&b, "[%d]", int(n))
+				} else {
+					appendPathKey(&b, fmt.Sprint(index.Value))
+				}
+			}
+		}
+		if b.Len() > 0 {
+			result = append(result, b.String())
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// parseSensitiveValues converts the sensitive_values of `terraform show -json`, which mirrors the
+// attribute tree with true at each sensitive value, into attribute paths.
+func parseSensitiveValues(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var tree interface{}
+	if err := json.Unmarshal(raw, &tree); err != nil {
+		return nil, fmt.Errorf("failed to parse sensitive_values: %w", err)
+	}
+	var result []string
+	collectSensitiveValues(tree, "", &result)
+	sort.Strings(result)
+	return result, nil
+}
+
+func collectSensitiveValues(node interface{}, path string, result *[]string) {
+	switch v := node.(type) {
+	case bool:
+		if v && path != "" {
+			*result = append(*result, path)
+		}
+	case map[string]interface{}:
+		for key, child := range v {
+			var b strings.Builder
+			b.WriteString(path)
+			appendPathKey(&b, key)
+			collectSensitiveValues(child, b.String(), result)
+		}
+	case []interface{}:
+		for i, child := range v {
+			collectSensitiveValues(child, fmt.Sprintf("%s[%d]", path, i), result)
+		}
+	}
+}
+
+func appendPathKey(b *strings.Builder, key string) {
+	if b.Len() > 0 {
+		b.WriteString(".")
+	}
+	b.WriteString(key)
+}
+
+// IsSensitive reports whether the state marks the attribute at path as sensitive, either directly,
+// through an enclosing attribute (tags covers tags.Name), or through a nested one (root_block_device
+// holds root_block_device[0].kms_key_id).
+func (r InstanceResource) IsSensitive(path string) bool {
+	return overlapsAny(r.Sensitive, path)
+}
+
+// overlapsAny reports whether path equals, encloses or lies beneath any of paths.
+func overlapsAny(paths []string, path string) bool {
+	for _, p := range paths {
+		if pathContains(p, path) || pathContains(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathContains reports whether path equals parent or lies beneath it.
+func pathContains(parent, path string) bool {
+	if !strings.HasPrefix(path, parent) {
+		return false
+	}
+	rest := path[len(parent):]
+	return rest == "" || strings.HasPrefix(rest, ".") || strings.HasPrefix(rest, "[")
+}
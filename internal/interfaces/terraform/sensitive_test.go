@@ -0,0 +1,65 @@
+package terraform
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTFState_SensitiveAttributes(t *testing.T) {
+	path := writeState(t, `{
+		"version": 4,
+		"resources": [
+			{"mode": "managed", "type": "aws_instance", "name": "web", "instances": [{
+				"attributes": {"id": "i-web", "instance_type": "t3.micro", "key_name": "ops-key", "tags": {"Name": "web", "Environment": "prod"}},
+				"sensitive_attributes": [
+					[{"type": "get_attr", "value": "tags"}, {"type": "index", "value": {"value": "Environment", "type": "string"}}],
+					[{"type": "get_attr", "value": "key_name"}],
+					[{"type": "get_attr", "value": "root_block_device"}, {"type": "index", "value": {"value": 0, "type": "number"}}, {"type": "get_attr", "value": "kms_key_id"}]
+				]
+			}]}
+		]
+	}`)
+	mockLog := &mockLogger{}
+
+	configSet, err := NewTFStateParser(mockLog).ParseTFState(path)
+	assert.NoError(t, err)
+
+	resource := configSet.Instances["i-web"]
+	assert.Equal(t, []string{"key_name", "root_block_device[0].kms_key_id", "tags.Environment"}, resource.Sensitive)
+	assert.True(t, resource.IsSensitive("tags.Environment"))
+	assert.True(t, resource.IsSensitive("root_block_device"))
+	assert.False(t, resource.IsSensitive("tags.Name"))
+	assert.True(t, resource.IsSensitive("tags"), "the map holds a sensitive value")
+	assert.False(t, resource.IsSensitive("instance_type"))
+
+	// Desired values are kept for comparison but never reach the logged aggregates
+	assert.Equal(t, "prod", resource.Config.Tags["Environment"])
+	assert.Equal(t, []string{"web"}, configSet.TagNames)
+	assert.Empty(t, configSet.TagEnvironments)
+	assert.Empty(t, configSet.KeyNames)
+	assert.Contains(t, mockLog.logs, "Parsed sensitive attributes, values withheld from logs and reports")
+}
+
+func TestParseSensitiveValues(t *testing.T) {
+	paths, err := parseSensitiveValues([]byte(`{"tags": {"Secret": true, "Name": false}, "root_block_device": [{}, {"kms_key_id": true}], "user_data": true}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"root_block_device[1].kms_key_id", "tags.Secret", "user_data"}, paths)
+}
+
+func TestShowJSONParser_SensitiveValues(t *testing.T) {
+	path := writeState(t, fmt.Sprintf(`{
+		"format_version": "1.0",
+		"values": {"root_module": {"resources": [
+			{"address": "aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web",
+				"values": {"id": "i-web", "tags": {"Name": "web", "Environment": "prod"}},
+				"sensitive_values": %s}
+		]}}
+	}`, `{"tags": {"Environment": true}}`))
+
+	configSet, err := NewShowJSONParser(&mockLogger{}, ShowCurrentState).ParseTFState(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tags.Environment"}, configSet.Instances["i-web"].Sensitive)
+	assert.Empty(t, configSet.TagEnvironments)
+}
@@ -0,0 +1,166 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cstudio7/drift-detector/internal/interfaces/logger"
+)
+
+// ShowValues selects which set of values a ShowJSONParser reads from `terraform show -json` output.
+type ShowValues string
+
+const (
+	// ShowCurrentState reads the current state: `values` of a state, or `prior_state` of a saved plan.
+	ShowCurrentState ShowValues = "state"
+
+	// ShowPlannedValues reads `planned_values` of a saved plan, i.e. the state after apply.
+	ShowPlannedValues ShowValues = "planned"
+)
+
+// ShowJSONParser is a TFStateParser for the output of `terraform show -json`.
+type ShowJSONParser struct {
+	logger  logger.Logger
+	values  ShowValues
+	sources *StateSources
+}
+
+// NewShowJSONParser creates a new ShowJSONParser reading the given set of values from local files.
+func NewShowJSONParser(logger logger.Logger, values ShowValues) *ShowJSONParser {
+	return &ShowJSONParser{
+		logger:  logger,
+		values:  values,
+		sources: NewStateSources(),
+	}
+}
+
+// WithSources makes the parser read locations through sources, e.g. s3:// URLs.
+func (p *ShowJSONParser) WithSources(sources *StateSources) *ShowJSONParser {
+	p.sources = sources
+	return p
+}
+
+// ShowJSON represents the subset of `terraform show -json` output used for drift detection.
+// State output only carries Values; plan output carries PlannedValues and PriorState.
+type ShowJSON struct {
+	FormatVersion    string          `json:"format_version"`
+	TerraformVersion string          `json:"terraform_version"`
+	Values           *ShowJSONValues `json:"values,omitempty"`
+	PlannedValues    *ShowJSONValues `json:"planned_values,omitempty"`
+	PriorState       *struct {
+		Values *ShowJSONValues `json:"values,omitempty"`
+	} `json:"prior_state,omitempty"`
+}
+
+// ShowJSONValues holds the root module of a values representation.
+type ShowJSONValues struct {
+	RootModule ShowJSONModule `json:"root_module"`
+}
+
+// ShowJSONModule is a module in a values representation, with its own resources and child modules.
+type ShowJSONModule struct {
+	Address      string             `json:"address,omitempty"`
+	Resources    []ShowJSONResource `json:"resources"`
+	ChildModules []ShowJSONModule   `json:"child_modules,omitempty"`
+}
+
+// ShowJSONResource is a single resource instance in a values representation.
+type ShowJSONResource struct {
+	Address string          `json:"address"`
+	Mode    string          `json:"mode"`
+	Type    string          `json:"type"`
+	Name    string          `json:"name"`
+	Index   interface{}     `json:"index,omitempty"`
+	Values  json.RawMessage `json:"values"`
+	// SensitiveValues mirrors Values with true at each sensitive attribute.
+	SensitiveValues json.RawMessage `json:"sensitive_values,omitempty"`
+}
+
+// ParseTFState reads `terraform show -json` output and returns the selected values as an InstanceConfigSet.
+func (p *ShowJSONParser) ParseTFState(filePath string) (InstanceConfigSet, error) {
+	location := RedactLocation(filePath)
+	p.logger.Info("Starting to parse show JSON file", "file_path", location, "values", p.values)
+
+	fileContent, err := readState(p.sources, filePath)
+	if err != nil {
+		p.logger.Error("Failed to read show JSON file", "file_path", location, "error", err.Error())
+		return InstanceConfigSet{}, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var show ShowJSON
+	if err := json.Unmarshal(fileContent, &show); err != nil {
+		p.logger.Error("Failed to parse show JSON file", "file_path", location, "error", err.Error())
+		return InstanceConfigSet{}, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if show.FormatVersion == "" {
+		p.logger.Error("File is not terraform show -json output", "file_path", location)
+		return InstanceConfigSet{}, fmt.Errorf("missing format_version: not terraform show -json output")
+	}
+
+	values, err := show.selectValues(p.values)
+	if err != nil {
+		p.logger.Error("Failed to select values", "file_path", location, "error", err.Error())
+		return InstanceConfigSet{}, err
+	}
+
+	configSet := InstanceConfigSet{
+		Format:    FormatShowJSON,
+		Instances: make(map[string]InstanceResource),
+	}
+	if values != nil {
+		if err := configSet.addModule(values.RootModule); err != nil {
+			p.logger.Error("Failed to read aws_instance resources", "file_path", location, "error", err.Error())
+			return InstanceConfigSet{}, err
+		}
+	}
+
+	p.logger.Info("Parsed show JSON", "format_version", show.FormatVersion, "terraform_version", show.TerraformVersion, "aws_instances", len(configSet.Instances))
+	return configSet, nil
+}
+
+// selectValues returns the values representation for the requested ShowValues.
+// A nil result with no error means the state holds no resources yet.
+func (s ShowJSON) selectValues(values ShowValues) (*ShowJSONValues, error) {
+	switch values {
+	case ShowPlannedValues:
+		if s.PlannedValues == nil {
+			return nil, fmt.Errorf("planned_values not found: input is not a saved plan")
+		}
+		return s.PlannedValues, nil
+	case ShowCurrentState:
+		if s.PriorState != nil {
+			return s.PriorState.Values, nil
+		}
+		if s.PlannedValues != nil {
+			// A plan against an empty state has no prior_state
+			return nil, nil
+		}
+		return s.Values, nil
+	default:
+		return nil, fmt.Errorf("unknown show values %q", values)
+	}
+}
+
+// addModule records every managed aws_instance in m and its child modules.
+func (c *InstanceConfigSet) addModule(m ShowJSONModule) error {
+	for _, resource := range m.Resources {
+		if resource.Mode != "managed" || resource.Type != "aws_instance" {
+			continue
+		}
+		r := InstanceResource{Module: m.Address, Type: resource.Type, Name: resource.Name, IndexKey: resource.Index}
+		sensitive, err := parseSensitiveValues(resource.SensitiveValues)
+		if err != nil {
+			return fmt.Errorf("%s: %w", resource.Address, err)
+		}
+		r.Sensitive = sensitive
+		if err := c.addInstance(r, resource.Values); err != nil {
+			return err
+		}
+	}
+	for _, child := range m.ChildModules {
+		if err := c.addModule(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package terraform
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShowJSONParser_ParseTFState(t *testing.T) {
+	mockLog := &mockLogger{}
+
+	t.Run("PlannedValues", func(t *testing.T) {
+		parser := NewShowJSONParser(mockLog, ShowPlannedValues)
+
+		configSet, err := parser.ParseTFState("../../../testdata/sample-show-plan.json")
+		assert.NoError(t, err)
+		assert.Equal(t, FormatShowJSON, configSet.Format)
+
+		// aws_instance.web[1] has no id yet and cannot be matched against AWS
+		assert.Len(t, configSet.Instances, 2)
+		assert.Equal(t, "t3.micro", configSet.Instances["i-06d8a793ad510fdea"].Config.InstanceType)
+		assert.Equal(t, "aws_instance.web[0]", configSet.Instances["i-06d8a793ad510fdea"].Address())
+		assert.Equal(t, "m5.large", configSet.Instances["i-015fdcf92ed95ad33"].Config.InstanceType)
+		assert.Equal(t, "module.db.aws_instance.primary", configSet.Instances["i-015fdcf92ed95ad33"].Address())
+	})
+
+	t.Run("PriorStateOfPlan", func(t *testing.T) {
+		parser := NewShowJSONParser(mockLog, ShowCurrentState)
+
+		configSet, err := parser.ParseTFState("../../../testdata/sample-show-plan.json")
+		assert.NoError(t, err)
+		assert.Len(t, configSet.Instances, 2)
+		assert.Equal(t, "t2.micro", configSet.Instances["i-06d8a793ad510fdea"].Config.InstanceType)
+		assert.Equal(t, []string{"t2.micro", "m5.large"}, configSet.InstanceTypes)
+	})
+
+	t.Run("StateOutput", func(t *testing.T) {
+		tempFile, err := os.CreateTemp("", "show_state_*.json")
+		assert.NoError(t, err)
+		defer os.Remove(tempFile.Name())
+
+		_, err = tempFile.WriteString(`{
+			"format_version": "1.0",
+			"values": {"root_module": {"resources": [
+				{"address": "aws_instance.app", "mode": "managed", "type": "aws_instance", "name": "app", "values": {"id": "i-app", "instance_type": "t2.small"}}
+			]}}
+		}`)
+		assert.NoError(t, err)
+		tempFile.Close()
+
+		configSet, err := NewShowJSONParser(mockLog, ShowCurrentState).ParseTFState(tempFile.Name())
+		assert.NoError(t, err)
+		assert.Len(t, configSet.Instances, 1)
+		assert.Equal(t, "t2.small", configSet.Instances["i-app"].Config.InstanceType)
+
+		// State output is not a plan
+		_, err = NewShowJSONParser(mockLog, ShowPlannedValues).ParseTFState(tempFile.Name())
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "planned_values not found")
+	})
+
+	t.Run("NotShowJSON", func(t *testing.T) {
+		parser := NewShowJSONParser(mockLog, ShowCurrentState)
+
+		_, err := parser.ParseTFState("../../../testdata/sample-tfstate.json")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "missing format_version")
+	})
+
+	t.Run("FileNotFound", func(t *testing.T) {
+		parser := NewShowJSONParser(mockLog, ShowCurrentState)
+
+		_, err := parser.ParseTFState("non_existent_file.json")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to read file")
+	})
+}
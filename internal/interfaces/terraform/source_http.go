@@ -0,0 +1,211 @@
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// HTTPBackendConfig configures access to a Terraform http backend. Its fields mirror the
+// backend's own settings and TF_HTTP_* environment variables.
+type HTTPBackendConfig struct {
+	Username      string
+	Password      string
+	LockAddress   string
+	UnlockAddress string
+	LockMethod    string
+	UnlockMethod  string
+	// Lock takes the state lock around the read, as Terraform does, so a state that an apply
+	// is holding is reported as locked instead of being read mid-write.
+	Lock   bool
+	Client *http.Client
+}
+
+// HTTPBackendConfigFromEnv reads the TF_HTTP_* variables Terraform uses for the http backend.
+func HTTPBackendConfigFromEnv() HTTPBackendConfig {
+	return HTTPBackendConfig{
+		Username:      os.Getenv("TF_HTTP_USERNAME"),
+		Password:      os.Getenv("TF_HTTP_PASSWORD"),
+		LockAddress:   os.Getenv("TF_HTTP_LOCK_ADDRESS"),
+		UnlockAddress: os.Getenv("TF_HTTP_UNLOCK_ADDRESS"),
+		LockMethod:    os.Getenv("TF_HTTP_LOCK_METHOD"),
+		UnlockMethod:  os.Getenv("TF_HTTP_UNLOCK_METHOD"),
+	}
+}
+
+// HTTPSource reads a state from a Terraform http backend, such as GitLab-managed state.
+type HTTPSource struct {
+	address string
+	config  HTTPBackendConfig
+}
+
+// NewHTTPSource creates a new HTTPSource for the state at address. Lock and unlock default to
+// the state address with the LOCK and UNLOCK methods; credentials in the URL override config.
+func NewHTTPSource(address *url.URL, config HTTPBackendConfig) *HTTPSource {
+	if address.User != nil {
+		config.Username = address.User.Username()
+		config.Password, _ = address.User.Password()
+		stripped := *address
+		stripped.User = nil
+		address = &stripped
+	}
+	if config.LockAddress == "" {
+		config.LockAddress = address.String()
+	}
+	if config.UnlockAddress == "" {
+		config.UnlockAddress = config.LockAddress
+	}
+	if config.LockMethod == "" {
+		config.LockMethod = "LOCK"
+	}
+	if config.UnlockMethod == "" {
+		config.UnlockMethod = "UNLOCK"
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	return &HTTPSource{
+		address: address.String(),
+		config:  config,
+	}
+}
+
+// HTTPSourceFactory returns a SourceFactory for http:// and https:// locations.
+func HTTPSourceFactory(config HTTPBackendConfig) SourceFactory {
+	return func(u *url.URL) (StateSource, error) {
+		return NewHTTPSource(u, config), nil
+	}
+}
+
+// Open fetches the state, holding the lock until the returned body is closed when locking is
+// enabled. The body is streamed rather than read into memory.
+func (s *HTTPSource) Open(ctx context.Context) (io.ReadCloser, error) {
+	return s.open(ctx, s.config.Lock)
+}
+
+// OpenUnlocked fetches the state without taking the lock, even when locking is enabled.
+func (s *HTTPSource) OpenUnlocked(ctx context.Context) (io.ReadCloser, error) {
+	return s.open(ctx, false)
+}
+
+func (s *HTTPSource) open(ctx context.Context, lock bool) (io.ReadCloser, error) {
+	unlock := func() {}
+	if lock {
+		lock, err := s.lock(ctx)
+		if err != nil {
+			return nil, err
+		}
+		unlock = func() { s.unlock(ctx, lock) }
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, s.address, nil)
+	if err != nil {
+		unlock()
+		return nil, fmt.Errorf("failed to get %s: %w", s, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return &lockedBody{ReadCloser: resp.Body, unlock: unlock}, nil
+	case http.StatusNoContent, http.StatusNotFound:
+		err = fmt.Errorf("no state stored at %s", s)
+	default:
+		err = fmt.Errorf("failed to get %s: unexpected status %s", s, resp.Status)
+	}
+	resp.Body.Close()
+	unlock()
+	return nil, err
+}
+
+// lockedBody is a state body read under the state lock, which Close releases.
+type lockedBody struct {
+	io.ReadCloser
+	unlock func()
+}
+
+func (b *lockedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.unlock()
+	return err
+}
+
+func (s *HTTPSource) String() string {
+	return s.address
+}
+
+// lock takes the state lock, returning a StateLockedError naming the holder if it is taken.
+func (s *HTTPSource) lock(ctx context.Context) (LockInfo, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return LockInfo{}, fmt.Errorf("failed to generate lock ID: %w", err)
+	}
+	hostname, _ := os.Hostname()
+	lock := LockInfo{
+		ID:        hex.EncodeToString(id),
+		Operation: "OperationTypeDriftDetection",
+		Who:       hostname,
+		Created:   time.Now().UTC().Format(time.RFC3339),
+		Path:      s.address,
+	}
+	payload, err := json.Marshal(lock)
+	if err != nil {
+		return LockInfo{}, err
+	}
+
+	resp, err := s.do(ctx, s.config.LockMethod, s.config.LockAddress, payload)
+	if err != nil {
+		return LockInfo{}, fmt.Errorf("failed to lock %s: %w", s, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return lock, nil
+	case http.StatusLocked, http.StatusConflict:
+		lockedErr := &StateLockedError{Source: s.address}
+		// The backend answers with the current holder's lock info when it has one
+		_ = json.NewDecoder(resp.Body).Decode(&lockedErr.Lock)
+		return LockInfo{}, lockedErr
+	default:
+		return LockInfo{}, fmt.Errorf("failed to lock %s: unexpected status %s", s, resp.Status)
+	}
+}
+
+// unlock releases a lock taken by lock. Failures are not fatal to the read.
+func (s *HTTPSource) unlock(ctx context.Context, lock LockInfo) {
+	payload, err := json.Marshal(lock)
+	if err != nil {
+		return
+	}
+	resp, err := s.do(ctx, s.config.UnlockMethod, s.config.UnlockAddress, payload)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+}
+
+func (s *HTTPSource) do(ctx context.Context, method, address string, payload []byte) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, address, body)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if s.config.Username != "" || s.config.Password != "" {
+		req.SetBasicAuth(s.config.Username, s.config.Password)
+	}
+	return s.config.Client.Do(req)
+}
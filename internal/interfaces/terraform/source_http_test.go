@@ -0,0 +1,223 @@
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+// httpBackend is a minimal stand-in for a Terraform http backend.
+type httpBackend struct {
+	mu      sync.Mutex
+	state   string
+	lock    *LockInfo
+	methods []string
+}
+
+func (b *httpBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.methods = append(b.methods, r.Method)
+
+	if user, pass, ok := r.BasicAuth(); !ok || user != "gitlab-ci-token" || pass != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if b.state == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, b.state)
+	case "LOCK":
+		if b.lock != nil {
+			w.WriteHeader(http.StatusLocked)
+			_ = json.NewEncoder(w).Encode(b.lock)
+			return
+		}
+		var lock LockInfo
+		_ = json.NewDecoder(r.Body).Decode(&lock)
+		b.lock = &lock
+	case "UNLOCK":
+		b.lock = nil
+	}
+}
+
+const httpBackendState = `{"version": 4, "resources": [
+	{"mode": "managed", "type": "aws_instance", "name": "web", "instances": [{"attributes": {"id": "i-web"}}]}
+]}`
+
+func newHTTPParser(config HTTPBackendConfig) *TFStateParserImpl {
+	sources := NewStateSources()
+	sources.Register("http", HTTPSourceFactory(config))
+	return NewTFStateParser(&mockLogger{}).WithSources(sources)
+}
+
+func TestHTTPSource_BasicAuth(t *testing.T) {
+	backend := &httpBackend{state: httpBackendState}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	configSet, err := newHTTPParser(HTTPBackendConfig{Username: "gitlab-ci-token", Password: "secret"}).ParseTFState(server.URL + "/state/prod")
+	assert.NoError(t, err)
+	assert.Contains(t, configSet.Instances, "i-web")
+	assert.Equal(t, []string{http.MethodGet}, backend.methods)
+
+	// Credentials may also be given in the URL
+	u, _ := url.Parse(server.URL + "/state/prod")
+	u.User = url.UserPassword("gitlab-ci-token", "secret")
+	_, err = newHTTPParser(HTTPBackendConfig{}).ParseTFState(u.String())
+	assert.NoError(t, err)
+
+	_, err = newHTTPParser(HTTPBackendConfig{Username: "gitlab-ci-token", Password: "wrong"}).ParseTFState(server.URL + "/state/prod")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
+
+func TestHTTPSource_NoState(t *testing.T) {
+	server := httptest.NewServer(&httpBackend{})
+	defer server.Close()
+
+	_, err := newHTTPParser(HTTPBackendConfig{Username: "gitlab-ci-token", Password: "secret"}).ParseTFState(server.URL + "/state/new")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no state stored")
+}
+
+func TestHTTPSource_Lock(t *testing.T) {
+	backend := &httpBackend{state: httpBackendState}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	config := HTTPBackendConfig{Username: "gitlab-ci-token", Password: "secret", Lock: true}
+
+	// An unlocked state is locked for the read and released afterwards
+	_, err := newHTTPParser(config).ParseTFState(server.URL + "/state/prod")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"LOCK", http.MethodGet, "UNLOCK"}, backend.methods)
+	assert.Nil(t, backend.lock)
+
+	// A state held by an apply is reported with the holder's lock info
+	backend.lock = &LockInfo{ID: "abc", Operation: "OperationTypeApply", Who: "ci@runner", Created: "2026-10-17T09:00:00Z"}
+	backend.methods = nil
+	_, err = newHTTPParser(config).ParseTFState(server.URL + "/state/prod")
+	assert.ErrorIs(t, err, entities.ErrStateLocked)
+	assert.Contains(t, err.Error(), "lock abc, OperationTypeApply by ci@runner")
+	assert.Equal(t, []string{"LOCK"}, backend.methods)
+}
+
+func TestHTTPSource_StreamsBody(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, httpBackendState[:20])
+		w.(http.Flusher).Flush()
+		<-release
+		_, _ = io.WriteString(w, httpBackendState[20:])
+	}))
+	defer server.Close()
+	defer close(release)
+
+	u, _ := url.Parse(server.URL + "/state/prod")
+	opened := make(chan io.ReadCloser, 1)
+	go func() {
+		reader, err := NewHTTPSource(u, HTTPBackendConfig{}).Open(context.Background())
+		assert.NoError(t, err)
+		opened <- reader
+	}()
+
+	// Open returns while the backend is still sending, instead of reading the whole state first
+	select {
+	case reader := <-opened:
+		defer reader.Close()
+		head := make([]byte, 20)
+		_, err := io.ReadFull(reader, head)
+		assert.NoError(t, err)
+		assert.Equal(t, httpBackendState[:20], string(head))
+	case <-time.After(5 * time.Second):
+		t.Fatal("Open waited for the whole body")
+	}
+}
+
+func TestHTTPSource_LockModeWarn(t *testing.T) {
+	backend := &httpBackend{state: httpBackendState, lock: &LockInfo{ID: "abc", Operation: "OperationTypeApply", Who: "ci@runner"}}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	sources := NewStateSources()
+	sources.Register("http", HTTPSourceFactory(HTTPBackendConfig{Username: "gitlab-ci-token", Password: "secret", Lock: true}))
+	mockLog := &mockLogger{}
+	parser := NewTFStateParser(mockLog).WithSources(sources)
+
+	// A held lock is logged and the state read without taking it
+	configSet, err := parser.WithLockPolicy(LockPolicy{Mode: LockModeWarn}).ParseTFState(server.URL + "/state/prod")
+	assert.NoError(t, err)
+	assert.Contains(t, configSet.Instances, "i-web")
+	assert.Contains(t, mockLog.logs, "State is locked, drift may include an operation in progress")
+	assert.Equal(t, []string{"LOCK", http.MethodGet}, backend.methods)
+	assert.Equal(t, "abc", backend.lock.ID)
+
+	backend.methods = nil
+	_, err = parser.WithLockPolicy(LockPolicy{Mode: LockModeAbort}).ParseTFState(server.URL + "/state/prod")
+	assert.ErrorIs(t, err, entities.ErrStateLocked)
+	assert.Equal(t, []string{"LOCK"}, backend.methods)
+}
+
+// recordingLogger records every message with its key-value pairs.
+type recordingLogger struct {
+	lines []string
+}
+
+func (l *recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprint(msg, keysAndValues))
+}
+
+func (l *recordingLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprint(msg, keysAndValues))
+}
+
+func (l *recordingLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprint(msg, keysAndValues))
+}
+
+func TestHTTPSource_CredentialsNotReported(t *testing.T) {
+	server := httptest.NewServer(&httpBackend{state: httpBackendState})
+	defer server.Close()
+
+	sources := NewStateSources()
+	sources.Register("http", HTTPSourceFactory(HTTPBackendConfig{}))
+	log := &recordingLogger{}
+	parser := NewTFStateParser(log).WithSources(sources).WithProgress(1, nil)
+
+	u, _ := url.Parse(server.URL + "/state/prod")
+	u.User = url.UserPassword("gitlab-ci-token", "secret")
+	configSet, err := parser.ParseTFState(u.String())
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/state/prod", configSet.States[0].Source)
+
+	// Failures name the state without its credentials too
+	u.User = url.UserPassword("gitlab-ci-token", "expired")
+	_, err = parser.ParseTFState(u.String())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+	assert.NotContains(t, err.Error(), "expired")
+
+	assert.Contains(t, log.lines, fmt.Sprint("Starting to parse file", []interface{}{"file_path", server.URL + "/state/prod"}))
+	assert.Contains(t, strings.Join(log.lines, "\n"), "Parsing state file[file_path "+server.URL+"/state/prod bytes_read")
+	for _, line := range log.lines {
+		assert.NotContains(t, line, "gitlab-ci-token")
+		assert.NotContains(t, line, "secret")
+		assert.NotContains(t, line, "expired")
+	}
+}
@@ -0,0 +1,147 @@
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+	"github.com/cstudio7/drift-detector/pkg/aws"
+)
+
+// defaultWorkspaceKeyPrefix is the S3 backend's default workspace_key_prefix.
+const defaultWorkspaceKeyPrefix = "env:"
+
+// s3LockFileSuffix is appended to the state key for the S3 backend's native lock file.
+const s3LockFileSuffix = ".tflock"
+
+// S3GetObjectAPI is the part of the S3 client an S3Source needs.
+type S3GetObjectAPI interface {
+	GetObject(ctx context.Context, params *aws.GetObjectInput, optFns ...func(*aws.S3Options)) (*aws.GetObjectOutput, error)
+}
+
+// S3Location identifies a state stored by the Terraform S3 backend.
+type S3Location struct {
+	Bucket             string `json:"bucket"`
+	Key                string `json:"key"`
+	WorkspaceKeyPrefix string `json:"workspace_key_prefix,omitempty"`
+	Workspace          string `json:"workspace,omitempty"`
+	VersionID          string `json:"version_id,omitempty"`
+}
+
+// ParseS3Location parses s3://bucket/key?workspace=w&workspace_key_prefix=p&version_id=v.
+func ParseS3Location(u *url.URL) (S3Location, error) {
+	location := S3Location{
+		Bucket:             u.Host,
+		Key:                strings.TrimPrefix(u.Path, "/"),
+		WorkspaceKeyPrefix: u.Query().Get("workspace_key_prefix"),
+		Workspace:          u.Query().Get("workspace"),
+		VersionID:          u.Query().Get("version_id"),
+	}
+	if location.Bucket == "" || location.Key == "" {
+		return S3Location{}, fmt.Errorf("%w: s3 location needs a bucket and key: %s", entities.ErrUnsupportedStateSource, u)
+	}
+	return location, nil
+}
+
+// ObjectKey returns the object key the S3 backend uses for the location's workspace:
+// the key itself for the default workspace, <workspace_key_prefix>/<workspace>/<key> otherwise.
+func (l S3Location) ObjectKey() string {
+	if l.Workspace == "" || l.Workspace == defaultWorkspace {
+		return l.Key
+	}
+	prefix := l.WorkspaceKeyPrefix
+	if prefix == "" {
+		prefix = defaultWorkspaceKeyPrefix
+	}
+	return path.Join(prefix, l.Workspace, l.Key)
+}
+
+// S3Source reads a state from the Terraform S3 backend.
+type S3Source struct {
+	client   S3GetObjectAPI
+	location S3Location
+}
+
+// NewS3Source creates a new S3Source.
+func NewS3Source(client S3GetObjectAPI, location S3Location) *S3Source {
+	return &S3Source{
+		client:   client,
+		location: location,
+	}
+}
+
+// S3SourceFactory returns a SourceFactory for s3:// locations read with client.
+func S3SourceFactory(client S3GetObjectAPI) SourceFactory {
+	return func(u *url.URL) (StateSource, error) {
+		location, err := ParseS3Location(u)
+		if err != nil {
+			return nil, err
+		}
+		return NewS3Source(client, location), nil
+	}
+}
+
+// Open fetches the state object, pinned to VersionID when one is set.
+func (s *S3Source) Open(ctx context.Context) (io.ReadCloser, error) {
+	input := &aws.GetObjectInput{
+		Bucket: aws.String(s.location.Bucket),
+		Key:    aws.String(s.location.ObjectKey()),
+	}
+	if s.location.VersionID != "" {
+		input.VersionId = aws.String(s.location.VersionID)
+	}
+	output, err := s.client.GetObject(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", s, err)
+	}
+	return output.Body, nil
+}
+
+func (s *S3Source) String() string {
+	str := "s3://" + s.location.Bucket + "/" + s.location.ObjectKey()
+	if s.location.VersionID != "" {
+		str += "?version_id=" + s.location.VersionID
+	}
+	return str
+}
+
+// LockInfo reads the lock file the S3 backend writes next to the state when use_lockfile is set.
+// A state pinned to a version is a past snapshot, so it is never locked. DynamoDB locks are not
+// visible from S3. Only a missing lock file means the state is unlocked: S3 answers 403 rather
+// than 404 for a missing object when s3:ListBucket is not granted, so a 403 leaves the lock
+// status unknown and is returned as an error.
+func (s *S3Source) LockInfo(ctx context.Context) (*LockInfo, error) {
+	if s.location.VersionID != "" {
+		return nil, nil
+	}
+	key := s.location.ObjectKey() + s3LockFileSuffix
+	output, err := s.client.GetObject(ctx, &aws.GetObjectInput{
+		Bucket: aws.String(s.location.Bucket),
+		Key:    aws.String(key),
+	})
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) {
+		switch statusErr.HTTPStatusCode() {
+		case http.StatusNotFound:
+			return nil, nil
+		case http.StatusForbidden:
+			return nil, fmt.Errorf("lock status unknown, access to s3://%s/%s denied: %w", s.location.Bucket, key, err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
+
+	lock := &LockInfo{Path: s.String()}
+	// The object's presence is the lock; its content only describes the holder
+	_ = json.NewDecoder(output.Body).Decode(lock)
+	return lock, nil
+}
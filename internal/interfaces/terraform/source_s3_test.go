@@ -0,0 +1,79 @@
+package terraform
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestS3Location_ObjectKey(t *testing.T) {
+	assert.Equal(t, "app/terraform.tfstate", S3Location{Key: "app/terraform.tfstate"}.ObjectKey())
+	assert.Equal(t, "app/terraform.tfstate", S3Location{Key: "app/terraform.tfstate", Workspace: "default"}.ObjectKey())
+	assert.Equal(t, "env:/staging/app/terraform.tfstate", S3Location{Key: "app/terraform.tfstate", Workspace: "staging"}.ObjectKey())
+	assert.Equal(t, "workspaces/prod/app/terraform.tfstate", S3Location{Key: "app/terraform.tfstate", Workspace: "prod", WorkspaceKeyPrefix: "workspaces"}.ObjectKey())
+}
+
+func TestParseS3Location(t *testing.T) {
+	u, _ := url.Parse("s3://my-states/app/terraform.tfstate?workspace=prod&workspace_key_prefix=workspaces&version_id=v2")
+	location, err := ParseS3Location(u)
+	assert.NoError(t, err)
+	assert.Equal(t, S3Location{Bucket: "my-states", Key: "app/terraform.tfstate", WorkspaceKeyPrefix: "workspaces", Workspace: "prod", VersionID: "v2"}, location)
+
+	u, _ = url.Parse("s3://my-states")
+	_, err = ParseS3Location(u)
+	assert.ErrorIs(t, err, entities.ErrUnsupportedStateSource)
+}
+
+// TestS3Source_EndpointOverride reads states from a local S3-compatible stand-in.
+func TestS3Source_EndpointOverride(t *testing.T) {
+	objects := map[string]string{
+		"/my-states/env:/staging/app/terraform.tfstate": `{"version": 4, "resources": [
+			{"mode": "managed", "type": "aws_instance", "name": "web", "instances": [{"attributes": {"id": "i-staging"}}]}
+		]}`,
+	}
+	var versionIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		versionIDs = append(versionIDs, r.URL.Query().Get("versionId"))
+		body, ok := objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `<Error><Code>NoSuchKey</Code><Message>not found</Message></Error>`)
+			return
+		}
+		_, _ = io.WriteString(w, body)
+	}))
+	defer server.Close()
+
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  aws.AnonymousCredentials{},
+	})
+	sources := NewStateSources()
+	sources.Register("s3", S3SourceFactory(client))
+	parser := NewTFStateParser(&mockLogger{}).WithSources(sources)
+
+	configSet, err := parser.ParseTFState("s3://my-states/app/terraform.tfstate?workspace=staging&version_id=v1")
+	assert.NoError(t, err)
+	assert.Contains(t, configSet.Instances, "i-staging")
+	assert.Equal(t, []string{"v1"}, versionIDs)
+
+	_, err = parser.ParseTFState("s3://my-states/app/terraform.tfstate")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read file")
+
+	source := NewS3Source(client, S3Location{Bucket: "my-states", Key: "app/terraform.tfstate", Workspace: "staging", VersionID: "v1"})
+	assert.Equal(t, "s3://my-states/env:/staging/app/terraform.tfstate?version_id=v1", source.String())
+	reader, err := source.Open(context.Background())
+	assert.NoError(t, err)
+	reader.Close()
+}
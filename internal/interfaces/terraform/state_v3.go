@@ -0,0 +1,153 @@
+package terraform
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TFStateV3 represents the structure of a legacy Terraform v3 state file (Terraform 0.11 and earlier).
+type TFStateV3 struct {
+	Version          int        `json:"version"`
+	TerraformVersion string     `json:"terraform_version"`
+	Serial           int        `json:"serial"`
+	Lineage          string     `json:"lineage"`
+	Modules          []ModuleV3 `json:"modules"`
+}
+
+// ModuleV3 is a module in a v3 state; Path is ["root", "app", ...] for module.app.
+type ModuleV3 struct {
+	Path      []string              `json:"path"`
+	Resources map[string]ResourceV3 `json:"resources"`
+}
+
+// ResourceV3 is a resource in a v3 state, keyed by "type.name" or "type.name.index".
+type ResourceV3 struct {
+	Type    string `json:"type"`
+	Primary *struct {
+		ID         string            `json:"id"`
+		Attributes map[string]string `json:"attributes"`
+	} `json:"primary"`
+}
+
+// addModuleV3 records the managed aws_instance resources of one v3 module.
+func (c *InstanceConfigSet) addModuleV3(module ModuleV3) error {
+	var modulePath []string
+	for _, name := range module.Path {
+		if name != "root" {
+			modulePath = append(modulePath, "module."+name)
+		}
+	}
+	for key, resource := range module.Resources {
+		if resource.Type != "aws_instance" || resource.Primary == nil || strings.HasPrefix(key, "data.") {
+			continue
+		}
+		r := InstanceResource{Module: strings.Join(modulePath, "."), Type: resource.Type}
+		parts := strings.SplitN(key, ".", 3)
+		if len(parts) < 2 {
+			continue
+		}
+		r.Name = parts[1]
+		if len(parts) == 3 {
+			if index, err := strconv.Atoi(parts[2]); err == nil {
+				r.IndexKey = float64(index)
+			}
+		}
+
+		attributes := unflatten(resource.Primary.Attributes)
+		if _, ok := attributes["id"]; !ok {
+			attributes["id"] = resource.Primary.ID
+		}
+		raw, err := json.Marshal(attributes)
+		if err != nil {
+			return err
+		}
+		if err := c.addInstance(r, raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unflatten rebuilds the nested attributes of a v3 flatmap, where "tags.%" counts a map,
+// "vpc_security_group_ids.#" counts a list or set, and elements follow as "name.key" or
+// "name.index.attribute". Leaf values stay strings.
+func unflatten(attrs map[string]string) map[string]interface{} {
+	return unflattenObject(attrs, "")
+}
+
+func unflattenObject(attrs map[string]string, prefix string) map[string]interface{} {
+	obj := make(map[string]interface{})
+	for key := range attrs {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		name := key[len(prefix):]
+		if i := strings.Index(name, "."); i >= 0 {
+			name = name[:i]
+		}
+		if _, done := obj[name]; done {
+			continue
+		}
+		full := prefix + name
+		if _, ok := attrs[full+".#"]; ok {
+			obj[name] = unflattenList(attrs, full)
+		} else if _, ok := attrs[full+".%"]; ok {
+			obj[name] = unflattenMap(attrs, full)
+		} else if value, ok := attrs[full]; ok {
+			obj[name] = value
+		}
+	}
+	return obj
+}
+
+// unflattenMap returns the map under prefix; its keys may themselves contain dots.
+func unflattenMap(attrs map[string]string, prefix string) map[string]interface{} {
+	m := make(map[string]interface{})
+	for key, value := range attrs {
+		if strings.HasPrefix(key, prefix+".") && key != prefix+".%" {
+			m[key[len(prefix)+1:]] = value
+		}
+	}
+	return m
+}
+
+// unflattenList returns the list or set under prefix, ordered by index; set elements are
+// keyed by hash and ordered by it.
+func unflattenList(attrs map[string]string, prefix string) []interface{} {
+	seen := make(map[string]bool)
+	var indexes []string
+	for key := range attrs {
+		if !strings.HasPrefix(key, prefix+".") || key == prefix+".#" {
+			continue
+		}
+		index := key[len(prefix)+1:]
+		if i := strings.Index(index, "."); i >= 0 {
+			index = index[:i]
+		}
+		if !seen[index] {
+			seen[index] = true
+			indexes = append(indexes, index)
+		}
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		a, errA := strconv.Atoi(indexes[i])
+		b, errB := strconv.Atoi(indexes[j])
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		return indexes[i] < indexes[j]
+	})
+
+	list := make([]interface{}, 0, len(indexes))
+	for _, index := range indexes {
+		element := prefix + "." + index
+		if value, ok := attrs[element]; ok {
+			list = append(list, value)
+		} else {
+			list = append(list, unflattenObject(attrs, element+"."))
+		}
+	}
+	return list
+}
@@ -0,0 +1,130 @@
+package terraform
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+)
+
+func writeState(t *testing.T, content string) string {
+	t.Helper()
+	tempFile, err := os.CreateTemp(t.TempDir(), "tfstate_*.json")
+	assert.NoError(t, err)
+	_, err = tempFile.WriteString(content)
+	assert.NoError(t, err)
+	tempFile.Close()
+	return tempFile.Name()
+}
+
+func TestParseTFState_V3(t *testing.T) {
+	path := writeState(t, `{
+		"version": 3,
+		"terraform_version": "0.11.14",
+		"serial": 12,
+		"lineage": "2f1c",
+		"modules": [
+			{"path": ["root"], "resources": {
+				"aws_instance.bastion": {"type": "aws_instance", "primary": {"id": "i-bastion", "attributes": {
+					"id": "i-bastion",
+					"instance_type": "t2.micro",
+					"subnet_id": "subnet-1",
+					"tags.%": "2",
+					"tags.Name": "bastion",
+					"tags.kubernetes.io/cluster": "owned",
+					"vpc_security_group_ids.#": "2",
+					"vpc_security_group_ids.3150231456": "sg-2",
+					"vpc_security_group_ids.1234567890": "sg-1",
+					"root_block_device.#": "1",
+					"root_block_device.0.volume_size": "8",
+					"root_block_device.0.volume_type": "gp2",
+					"root_block_device.0.iops": "100",
+					"root_block_device.0.encrypted": "true"
+				}}},
+				"data.aws_instance.lookup": {"type": "aws_instance", "primary": {"id": "i-data", "attributes": {"id": "i-data"}}},
+				"aws_s3_bucket.logs": {"type": "aws_s3_bucket", "primary": {"id": "logs", "attributes": {"id": "logs"}}}
+			}},
+			{"path": ["root", "app"], "resources": {
+				"aws_instance.web.1": {"type": "aws_instance", "primary": {"id": "i-web1", "attributes": {"instance_type": "t3.small"}}}
+			}}
+		]
+	}`)
+
+	configSet, err := NewTFStateParser(&mockLogger{}).ParseTFState(path)
+	assert.NoError(t, err)
+	assert.Equal(t, FormatV3, configSet.Format)
+	assert.Len(t, configSet.Instances, 2)
+
+	bastion := configSet.Instances["i-bastion"]
+	assert.Equal(t, "aws_instance.bastion", bastion.Address())
+	assert.Equal(t, "t2.micro", bastion.Config.InstanceType)
+	assert.Equal(t, map[string]string{"Name": "bastion", "kubernetes.io/cluster": "owned"}, bastion.Config.Tags)
+	assert.Equal(t, []string{"sg-1", "sg-2"}, bastion.Config.SecurityGroupIDs)
+	assert.Equal(t, []entities.EBSBlockDevice{{VolumeSize: 8, VolumeType: "gp2", Iops: 100, Encrypted: true, Root: true}}, bastion.Config.EBSBlockDevices)
+
+	// The primary ID stands in when the flatmap has no id attribute
+	web := configSet.Instances["i-web1"]
+	assert.Equal(t, "module.app.aws_instance.web[1]", web.Address())
+	assert.Equal(t, "t3.small", web.Config.InstanceType)
+}
+
+func TestParseTFState_OpenTofu(t *testing.T) {
+	path := writeState(t, `{
+		"version": 4,
+		"terraform_version": "1.8.2",
+		"resources": [
+			{"mode": "managed", "type": "aws_instance", "name": "web", "provider": "provider[\"registry.opentofu.org/hashicorp/aws\"]",
+				"instances": [{"attributes": {"id": "i-tofu", "instance_type": "t3.micro"}}]}
+		]
+	}`)
+
+	configSet, err := NewTFStateParser(&mockLogger{}).ParseTFState(path)
+	assert.NoError(t, err)
+	assert.Equal(t, FormatOpenTofu, configSet.Format)
+	assert.Equal(t, "t3.micro", configSet.Instances["i-tofu"].Config.InstanceType)
+}
+
+func TestParseTFState_UnsupportedVersion(t *testing.T) {
+	parser := NewTFStateParser(&mockLogger{})
+
+	t.Run("UnknownVersion", func(t *testing.T) {
+		_, err := parser.ParseTFState(writeState(t, `{"version": 5, "resources": []}`))
+
+		var versionErr *UnsupportedStateVersionError
+		assert.True(t, errors.As(err, &versionErr))
+		assert.Equal(t, 5, versionErr.Version)
+		assert.ErrorIs(t, err, entities.ErrUnsupportedStateVersion)
+		assert.EqualError(t, err, "unsupported state version 5")
+	})
+
+	t.Run("EncryptedOpenTofuState", func(t *testing.T) {
+		_, err := parser.ParseTFState(writeState(t, `{"serial": 3, "lineage": "x", "encryption_version": "v0", "encrypted_data": "c2VjcmV0"}`))
+
+		assert.ErrorIs(t, err, entities.ErrUnsupportedStateVersion)
+		assert.Contains(t, err.Error(), "encrypted by OpenTofu")
+	})
+}
+
+func TestUnflatten(t *testing.T) {
+	attrs := unflatten(map[string]string{
+		"id":                              "i-1",
+		"ebs_block_device.#":              "2",
+		"ebs_block_device.10.device_name": "/dev/sdc",
+		"ebs_block_device.2.device_name":  "/dev/sdb",
+		"ebs_block_device.2.tags.%":       "1",
+		"ebs_block_device.2.tags.Name":    "data",
+		"security_groups.#":               "0",
+	})
+
+	assert.Equal(t, map[string]interface{}{
+		"id": "i-1",
+		"ebs_block_device": []interface{}{
+			map[string]interface{}{"device_name": "/dev/sdb", "tags": map[string]interface{}{"Name": "data"}},
+			map[string]interface{}{"device_name": "/dev/sdc"},
+		},
+		"security_groups": []interface{}{},
+	}, attrs)
+}
@@ -0,0 +1,334 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+)
+
+// TFStateV4 represents the structure of a Terraform v4 state file.
+type TFStateV4 struct {
+	Version          int          `json:"version"`
+	TerraformVersion string       `json:"terraform_version"`
+	Serial           int          `json:"serial"`
+	Lineage          string       `json:"lineage"`
+	Resources        []ResourceV4 `json:"resources"`
+}
+
+// ResourceV4 is a single resource block in a v4 state.
+type ResourceV4 struct {
+	Module    string       `json:"module,omitempty"`
+	Mode      string       `json:"mode"`
+	Type      string       `json:"type"`
+	Name      string       `json:"name"`
+	Provider  string       `json:"provider"`
+	Instances []InstanceV4 `json:"instances"`
+}
+
+// InstanceV4 is one instance of a v4 resource, created by count or for_each.
+type InstanceV4 struct {
+	IndexKey            interface{}     `json:"index_key,omitempty"`
+	Attributes          json.RawMessage `json:"attributes"`
+	SensitiveAttributes json.RawMessage `json:"sensitive_attributes,omitempty"`
+}
+
+// openTofuRegistry is the provider host in OpenTofu state provider addresses.
+const openTofuRegistry = "registry.opentofu.org/"
+
+// awsInstanceAttributes holds the aws_instance attributes used for drift detection.
+type awsInstanceAttributes struct {
+	ID                  string                 `json:"id"`
+	InstanceType        string                 `json:"instance_type"`
+	AMI                 string                 `json:"ami"`
+	AvailabilityZone    string                 `json:"availability_zone"`
+	KeyName             string                 `json:"key_name"`
+	Tags                map[string]string      `json:"tags"`
+	TagsAll             map[string]string      `json:"tags_all"`
+	SecurityGroups      []string               `json:"security_groups"`
+	VPCSecurityGroupIDs []string               `json:"vpc_security_group_ids"`
+	SubnetID            string                 `json:"subnet_id"`
+	IAMInstanceProfile  string                 `json:"iam_instance_profile"`
+	RootBlockDevice     []blockDeviceAttribute `json:"root_block_device"`
+	EBSBlockDevice      []blockDeviceAttribute `json:"ebs_block_device"`
+}
+
+type blockDeviceAttribute struct {
+	DeviceName string   `json:"device_name"`
+	VolumeSize flexInt  `json:"volume_size"`
+	VolumeType string   `json:"volume_type"`
+	Iops       flexInt  `json:"iops"`
+	Throughput flexInt  `json:"throughput"`
+	Encrypted  flexBool `json:"encrypted"`
+	KMSKeyID   string   `json:"kms_key_id"`
+	VolumeID   string   `json:"volume_id"`
+}
+
+func (bd blockDeviceAttribute) toBlockDevice(root bool) entities.EBSBlockDevice {
+	return entities.EBSBlockDevice{
+		DeviceName: bd.DeviceName,
+		VolumeSize: int(bd.VolumeSize),
+		VolumeType: bd.VolumeType,
+		Iops:       int(bd.Iops),
+		Throughput: int(bd.Throughput),
+		Encrypted:  bool(bd.Encrypted),
+		KMSKeyID:   bd.KMSKeyID,
+		VolumeID:   bd.VolumeID,
+		Root:       root,
+	}
+}
+
+// flexInt decodes a JSON number or a numeric string, since v3 states store every attribute as a string.
+type flexInt int
+
+func (i *flexInt) UnmarshalJSON(data []byte) error {
+	var n int
+	if err := json.Unmarshal(data, &n); err == nil {
+		*i = flexInt(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		*i = 0
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*i = flexInt(n)
+	return nil
+}
+
+// flexBool decodes a JSON boolean or a "true"/"false" string, for the same reason as flexInt.
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	var v bool
+	if err := json.Unmarshal(data, &v); err == nil {
+		*b = flexBool(v)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		*b = false
+		return nil
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*b = flexBool(v)
+	return nil
+}
+
+// toInstanceConfig converts state attributes to an InstanceConfig.
+func (a awsInstanceAttributes) toInstanceConfig() entities.InstanceConfig {
+	config := entities.InstanceConfig{
+		InstanceID:         a.ID,
+		InstanceType:       a.InstanceType,
+		AMI:                a.AMI,
+		AvailabilityZone:   a.AvailabilityZone,
+		KeyName:            a.KeyName,
+		Tags:               make(map[string]string),
+		SecurityGroupIDs:   a.VPCSecurityGroupIDs,
+		SubnetID:           a.SubnetID,
+		IAMInstanceProfile: a.IAMInstanceProfile,
+	}
+	// Older states and default-VPC instances only carry security_groups
+	if len(config.SecurityGroupIDs) == 0 {
+		config.SecurityGroupIDs = a.SecurityGroups
+	}
+	// tags_all adds the provider's default_tags, which AWS returns like any other tag
+	tags := a.TagsAll
+	if len(tags) == 0 {
+		tags = a.Tags
+	}
+	for k, v := range tags {
+		config.Tags[k] = v
+	}
+	for _, bd := range a.RootBlockDevice {
+		config.EBSBlockDevices = append(config.EBSBlockDevices, bd.toBlockDevice(true))
+	}
+	for _, bd := range a.EBSBlockDevice {
+		config.EBSBlockDevices = append(config.EBSBlockDevices, bd.toBlockDevice(false))
+	}
+	return config
+}
+
+// networkInterfaceAttributes holds the aws_network_interface attributes that tie an interface to
+// an instance.
+type networkInterfaceAttributes struct {
+	ID             string   `json:"id"`
+	SecurityGroups []string `json:"security_groups"`
+	Attachment     []struct {
+		Instance    string  `json:"instance"`
+		DeviceIndex flexInt `json:"device_index"`
+	} `json:"attachment"`
+}
+
+// networkInterfaces collects the secondary network interfaces of a state by the instance they are
+// attached to. Interfaces are separate resources, so they are attached to the instances once the
+// whole state is read.
+type networkInterfaces map[string][]entities.NetworkInterface
+
+// add decodes raw aws_network_interface attributes. The primary interface (device index 0) is
+// left out, since its groups are the instance's vpc_security_group_ids.
+func (n networkInterfaces) add(attributes json.RawMessage) error {
+	var attrs networkInterfaceAttributes
+	if err := json.Unmarshal(attributes, &attrs); err != nil {
+		return err
+	}
+	for _, attachment := range attrs.Attachment {
+		if attachment.Instance == "" || attachment.DeviceIndex == 0 {
+			continue
+		}
+		n[attachment.Instance] = append(n[attachment.Instance], entities.NetworkInterface{
+			ID:               attrs.ID,
+			DeviceIndex:      int(attachment.DeviceIndex),
+			SecurityGroupIDs: attrs.SecurityGroups,
+		})
+	}
+	return nil
+}
+
+// attachNetworkInterfaces sets the secondary network interfaces of each instance in c.
+func (c *InstanceConfigSet) attachNetworkInterfaces(interfaces networkInterfaces) {
+	for id, attached := range interfaces {
+		r, ok := c.Instances[id]
+		if !ok {
+			continue
+		}
+		sort.Slice(attached, func(i, j int) bool { return attached[i].DeviceIndex < attached[j].DeviceIndex })
+		r.Config.NetworkInterfaces = attached
+		c.Instances[id] = r
+	}
+}
+
+// addInstance decodes raw aws_instance attributes into r and records it under its instance ID.
+// Instances without an ID (not yet created) are skipped.
+func (c *InstanceConfigSet) addInstance(r InstanceResource, attributes json.RawMessage) error {
+	var attrs awsInstanceAttributes
+	if err := json.Unmarshal(attributes, &attrs); err != nil {
+		return fmt.Errorf("failed to parse attributes of %s: %w", r.Address(), err)
+	}
+	if attrs.ID == "" {
+		return nil
+	}
+	r.Config = attrs.toInstanceConfig()
+	c.Instances[attrs.ID] = r
+	c.add(r)
+	return nil
+}
+
+// sensitiveCount returns how many sensitive attribute paths the set's resources carry.
+func (c InstanceConfigSet) sensitiveCount() int {
+	count := 0
+	for _, r := range c.Instances {
+		count += len(r.Sensitive)
+	}
+	return count
+}
+
+// Merge adds the resources and aggregated attributes of other to c. Instance IDs that c already
+// holds keep their existing resource and are returned so the caller can report the conflict.
+func (c *InstanceConfigSet) Merge(other InstanceConfigSet) (conflicts []string) {
+	if c.Format == "" {
+		c.Format = other.Format
+	}
+	if len(other.Instances) > 0 && c.Instances == nil {
+		c.Instances = make(map[string]InstanceResource, len(other.Instances))
+	}
+	for id, resource := range other.Instances {
+		if _, ok := c.Instances[id]; ok {
+			conflicts = append(conflicts, id)
+			continue
+		}
+		c.Instances[id] = resource
+	}
+	c.States = append(c.States, other.States...)
+	c.InstanceTypes = appendUnique(c.InstanceTypes, other.InstanceTypes...)
+	c.AMIs = appendUnique(c.AMIs, other.AMIs...)
+	c.AvailabilityZones = appendUnique(c.AvailabilityZones, other.AvailabilityZones...)
+	c.KeyNames = appendUnique(c.KeyNames, other.KeyNames...)
+	c.SecurityGroupIDs = appendUnique(c.SecurityGroupIDs, other.SecurityGroupIDs...)
+	c.SubnetIDs = appendUnique(c.SubnetIDs, other.SubnetIDs...)
+	c.IAMInstanceProfiles = appendUnique(c.IAMInstanceProfiles, other.IAMInstanceProfiles...)
+	c.TagNames = appendUnique(c.TagNames, other.TagNames...)
+	c.TagEnvironments = appendUnique(c.TagEnvironments, other.TagEnvironments...)
+	for _, size := range other.EBSVolumeSizes {
+		if !containsInt(c.EBSVolumeSizes, size) {
+			c.EBSVolumeSizes = append(c.EBSVolumeSizes, size)
+		}
+	}
+	c.EBSVolumeTypes = appendUnique(c.EBSVolumeTypes, other.EBSVolumeTypes...)
+	sort.Strings(conflicts)
+	return conflicts
+}
+
+// add merges a single instance's attributes into the aggregated lists. Sensitive attributes are
+// left out, since the lists are logged.
+func (c *InstanceConfigSet) add(r InstanceResource) {
+	config := r.Config
+	addValue := func(list []string, path string, values ...string) []string {
+		if r.IsSensitive(path) {
+			return list
+		}
+		return appendUnique(list, values...)
+	}
+	c.InstanceTypes = addValue(c.InstanceTypes, "instance_type", config.InstanceType)
+	c.AMIs = addValue(c.AMIs, "ami", config.AMI)
+	c.AvailabilityZones = addValue(c.AvailabilityZones, "availability_zone", config.AvailabilityZone)
+	c.KeyNames = addValue(c.KeyNames, "key_name", config.KeyName)
+	if !r.IsSensitive("security_groups") {
+		c.SecurityGroupIDs = addValue(c.SecurityGroupIDs, "vpc_security_group_ids", config.SecurityGroupIDs...)
+	}
+	c.SubnetIDs = addValue(c.SubnetIDs, "subnet_id", config.SubnetID)
+	c.IAMInstanceProfiles = addValue(c.IAMInstanceProfiles, "iam_instance_profile", config.IAMInstanceProfile)
+	c.TagNames = addValue(c.TagNames, "tags.Name", config.Tags["Name"])
+	c.TagEnvironments = addValue(c.TagEnvironments, "tags.Environment", config.Tags["Environment"])
+	if r.IsSensitive("root_block_device") || r.IsSensitive("ebs_block_device") {
+		return
+	}
+	for _, ebs := range config.EBSBlockDevices {
+		if !containsInt(c.EBSVolumeSizes, ebs.VolumeSize) {
+			c.EBSVolumeSizes = append(c.EBSVolumeSizes, ebs.VolumeSize)
+		}
+		c.EBSVolumeTypes = appendUnique(c.EBSVolumeTypes, ebs.VolumeType)
+	}
+}
+
+func appendUnique(slice []string, items ...string) []string {
+	for _, item := range items {
+		if !contains(slice, item) {
+			slice = append(slice, item)
+		}
+	}
+	return slice
+}
+
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(slice []int, item int) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,296 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ProgressFunc is called while a state is read with the bytes read so far and the total size,
+// or -1 when the source does not know its size.
+type ProgressFunc func(location string, read, total int64)
+
+// defaultProgressInterval is how many bytes are read between progress reports.
+const defaultProgressInterval = 64 << 20
+
+// stateHeader is the top-level metadata of a state document.
+type stateHeader struct {
+	Version          int
+	TerraformVersion string
+	Serial           int
+	Lineage          string
+	// legacyLayout is set when resources is the legacy aggregated object rather than an array.
+	legacyLayout bool
+	encrypted    bool
+	openTofu     bool
+}
+
+// decodeState walks a state document token by token. Only aws_instance resources, and the network
+// interfaces attached to them, are decoded; every other resource is skipped without being held in
+// memory, so memory use depends on the largest aws_instance rather than on the size of the state.
+func decodeState(r io.Reader) (stateHeader, InstanceConfigSet, error) {
+	var header stateHeader
+	configSet := InstanceConfigSet{
+		Instances: make(map[string]InstanceResource),
+	}
+	interfaces := make(networkInterfaces)
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return header, configSet, err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return header, configSet, err
+		}
+		switch key {
+		case "version":
+			err = dec.Decode(&header.Version)
+		case "terraform_version":
+			err = dec.Decode(&header.TerraformVersion)
+		case "serial":
+			err = dec.Decode(&header.Serial)
+		case "lineage":
+			err = dec.Decode(&header.Lineage)
+		case "encrypted_data":
+			header.encrypted = true
+			err = skipValue(dec)
+		case "resources":
+			err = decodeResources(dec, &header, &configSet, interfaces)
+		case "modules":
+			err = decodeModulesV3(dec, &configSet)
+		default:
+			err = skipValue(dec)
+		}
+		if err != nil {
+			return header, configSet, err
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return header, configSet, err
+	}
+	configSet.attachNetworkInterfaces(interfaces)
+	return header, configSet, nil
+}
+
+// decodeResources reads the v4 resources array one resource at a time, or the legacy
+// resources object of aggregated lists.
+func decodeResources(dec *json.Decoder, header *stateHeader, configSet *InstanceConfigSet, interfaces networkInterfaces) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch t {
+	case json.Delim('['):
+		for dec.More() {
+			if err := decodeResourceV4(dec, header, configSet, interfaces); err != nil {
+				return err
+			}
+		}
+		return expectDelim(dec, ']')
+	case json.Delim('{'):
+		header.legacyLayout = true
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if key == "aws_instance" {
+				err = dec.Decode(configSet)
+			} else {
+				err = skipValue(dec)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return expectDelim(dec, '}')
+	case nil:
+		return nil
+	default:
+		return fmt.Errorf("unexpected resources value %v", t)
+	}
+}
+
+// decodeResourceV4 reads one v4 resource. Instances are decoded only for managed aws_instance
+// resources, and aws_network_interface resources whose attachments are collected in interfaces;
+// Terraform writes type before instances, so other resources are skipped unread.
+func decodeResourceV4(dec *json.Decoder, header *stateHeader, configSet *InstanceConfigSet, interfaces networkInterfaces) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	var resource ResourceV4
+	var pending []InstanceV4
+	typeKnown := false
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "module":
+			err = dec.Decode(&resource.Module)
+		case "mode":
+			err = dec.Decode(&resource.Mode)
+		case "type":
+			err = dec.Decode(&resource.Type)
+			typeKnown = true
+		case "name":
+			err = dec.Decode(&resource.Name)
+		case "provider":
+			err = dec.Decode(&resource.Provider)
+		case "instances":
+			if typeKnown && resource.Type != "aws_instance" && resource.Type != "aws_network_interface" {
+				err = skipValue(dec)
+			} else {
+				err = dec.Decode(&pending)
+			}
+		default:
+			err = skipValue(dec)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return err
+	}
+
+	if strings.Contains(resource.Provider, openTofuRegistry) {
+		header.openTofu = true
+	}
+	if resource.Mode != "managed" {
+		return nil
+	}
+	if resource.Type == "aws_network_interface" {
+		for _, instance := range pending {
+			if err := interfaces.add(instance.Attributes); err != nil {
+				return fmt.Errorf("failed to parse attributes of %s.%s: %w", resource.Type, resource.Name, err)
+			}
+		}
+		return nil
+	}
+	if resource.Type != "aws_instance" {
+		return nil
+	}
+	for _, instance := range pending {
+		r := InstanceResource{Module: resource.Module, Type: resource.Type, Name: resource.Name, IndexKey: indexKey(instance.IndexKey)}
+		sensitive, err := parseSensitiveAttributes(instance.SensitiveAttributes)
+		if err != nil {
+			return fmt.Errorf("%s: %w", r.Address(), err)
+		}
+		r.Sensitive = sensitive
+		if err := configSet.addInstance(r, instance.Attributes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeModulesV3 reads the v3 modules array one module at a time.
+func decodeModulesV3(dec *json.Decoder, configSet *InstanceConfigSet) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+	for dec.More() {
+		var module ModuleV3
+		if err := dec.Decode(&module); err != nil {
+			return err
+		}
+		if err := configSet.addModuleV3(module); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+// indexKey converts a count index decoded as json.Number to the float64 that Address expects.
+func indexKey(key interface{}) interface{} {
+	if n, ok := key.(json.Number); ok {
+		f, err := n.Float64()
+		if err == nil {
+			return f
+		}
+	}
+	return key
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if t != delim {
+		return fmt.Errorf("expected %v, got %v", delim, t)
+	}
+	return nil
+}
+
+// skipValue consumes the next value, descending through objects and arrays token by token.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// progressReader reports the bytes read from a state every interval bytes.
+type progressReader struct {
+	reader   io.Reader
+	location string
+	total    int64
+	read     int64
+	next     int64
+	interval int64
+	progress ProgressFunc
+	// err records a failure of the underlying reader, as opposed to malformed JSON.
+	err error
+}
+
+func newProgressReader(reader io.Reader, location string, interval int64, progress ProgressFunc) *progressReader {
+	total := int64(-1)
+	if f, ok := reader.(*os.File); ok {
+		if info, err := f.Stat(); err == nil {
+			total = info.Size()
+		}
+	}
+	return &progressReader{
+		reader:   reader,
+		location: location,
+		total:    total,
+		next:     interval,
+		interval: interval,
+		progress: progress,
+	}
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	if r.progress != nil && r.interval > 0 && r.read >= r.next {
+		r.progress(r.location, r.read, r.total)
+		for r.next <= r.read {
+			r.next += r.interval
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,150 @@
+package terraform
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeLargeState writes a v4 state of n resources, of which instances, spread evenly, are
+// aws_instance resources.
+func writeLargeState(tb testing.TB, n, instances int) string {
+	tb.Helper()
+	path := filepath.Join(tb.TempDir(), "terraform.tfstate")
+	f, err := os.Create(path)
+	assert.NoError(tb, err)
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	fmt.Fprint(w, `{"version": 4, "terraform_version": "1.7.5", "serial": 1, "lineage": "bench", "resources": [`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			fmt.Fprint(w, ",")
+		}
+		if i%(n/instances) == 0 && i/(n/instances) < instances {
+			fmt.Fprintf(w, `{"mode": "managed", "type": "aws_instance", "name": "node", "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+				"instances": [{"index_key": %d, "attributes": {"id": "i-%08d", "instance_type": "t3.micro", "subnet_id": "subnet-1",
+				"vpc_security_group_ids": ["sg-1"], "tags": {"Name": "node-%d"}}}]}`, i, i, i)
+			continue
+		}
+		fmt.Fprintf(w, `{"mode": "managed", "type": "aws_security_group_rule", "name": "rule", "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+			"instances": [{"index_key": %d, "attributes": {"id": "sgrule-%d", "cidr_blocks": ["10.0.0.0/8", "172.16.0.0/12"], "description": "allow internal traffic from the peered networks",
+			"from_port": 443, "to_port": 443, "protocol": "tcp", "security_group_id": "sg-1", "type": "ingress"}, "dependencies": ["aws_security_group.main"]}]}`, i, i)
+	}
+	fmt.Fprint(w, `]}`)
+	assert.NoError(tb, w.Flush())
+	return path
+}
+
+func TestParseTFState_ReportsProgress(t *testing.T) {
+	path := writeLargeState(t, 500, 5)
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+
+	var reads []int64
+	parser := NewTFStateParser(&mockLogger{}).WithProgress(16<<10, func(location string, read, total int64) {
+		assert.Equal(t, path, location)
+		assert.Equal(t, info.Size(), total)
+		reads = append(reads, read)
+	})
+
+	configSet, err := parser.ParseTFState(path)
+	assert.NoError(t, err)
+	assert.Len(t, configSet.Instances, 5)
+	assert.Equal(t, "aws_instance.node[200]", configSet.Instances["i-00000200"].Address())
+
+	assert.GreaterOrEqual(t, len(reads), int(info.Size()/(16<<10))-1)
+	assert.IsIncreasing(t, reads)
+}
+
+func TestParseTFState_LogsProgressByDefault(t *testing.T) {
+	mockLog := &mockLogger{}
+	parser := NewTFStateParser(mockLog)
+	parser.progressInterval = 1 << 10
+
+	_, err := parser.ParseTFState(writeLargeState(t, 10, 1))
+	assert.NoError(t, err)
+	assert.Contains(t, mockLog.logs, "Parsing state file")
+}
+
+func TestDecodeState_InstancesBeforeType(t *testing.T) {
+	path := writeState(t, `{
+		"resources": [
+			{"instances": [{"attributes": {"id": "i-late"}}], "mode": "managed", "name": "web", "type": "aws_instance"},
+			{"instances": [{"attributes": {"id": "bucket"}}], "mode": "managed", "name": "logs", "type": "aws_s3_bucket"}
+		],
+		"version": 4
+	}`)
+
+	configSet, err := NewTFStateParser(&mockLogger{}).ParseTFState(path)
+	assert.NoError(t, err)
+	assert.Equal(t, FormatV4, configSet.Format)
+	assert.Len(t, configSet.Instances, 1)
+	assert.Equal(t, "aws_instance.web", configSet.Instances["i-late"].Address())
+}
+
+// peakHeap parses the state at path and returns the peak live heap above the baseline, sampled
+// every twentieth of the file.
+func peakHeap(tb testing.TB, path string) uint64 {
+	tb.Helper()
+	info, err := os.Stat(path)
+	assert.NoError(tb, err)
+
+	var peak uint64
+	var stats runtime.MemStats
+	sample := func(string, int64, int64) {
+		runtime.GC()
+		runtime.ReadMemStats(&stats)
+		peak = max(peak, stats.HeapAlloc)
+	}
+	parser := NewTFStateParser(&mockLogger{}).WithProgress(info.Size()/20, sample)
+
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	base := stats.HeapAlloc
+	_, err = parser.ParseTFState(path)
+	assert.NoError(tb, err)
+	if peak < base {
+		return 0
+	}
+	return peak - base
+}
+
+func TestParseTFState_MemoryIsFlat(t *testing.T) {
+	if testing.Short() {
+		t.Skip("writes a large state")
+	}
+	// The same instances among 50 times as many skipped resources
+	small := peakHeap(t, writeLargeState(t, 2000, 20))
+	large := peakHeap(t, writeLargeState(t, 100000, 20))
+	assert.LessOrEqual(t, large, 2*small+256<<10, "peak heap grew from %d to %d bytes", small, large)
+}
+
+// BenchmarkParseTFState reports the peak live heap while parsing states of growing size with the
+// same 100 aws_instance resources. Everything else is skipped, so the peak stays flat.
+func BenchmarkParseTFState(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("resources=%d", n), func(b *testing.B) {
+			path := writeLargeState(b, n, 100)
+			info, err := os.Stat(path)
+			assert.NoError(b, err)
+			parser := NewTFStateParser(&mockLogger{})
+
+			b.SetBytes(info.Size())
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := parser.ParseTFState(path); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+
+			b.ReportMetric(float64(peakHeap(b, path))/(1<<20), "peak-heap-MB")
+		})
+	}
+}
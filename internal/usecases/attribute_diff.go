@@ -0,0 +1,364 @@
+package usecases
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+)
+
+// AttributeChange is one difference found by DiffAttributes. From is nil for added attributes and
+// To is nil for removed ones; a whole added or removed map or list is reported once, at its path.
+type AttributeChange struct {
+	Path string              `json:"path"`
+	Kind entities.ChangeKind `json:"kind"`
+	From interface{}         `json:"from,omitempty"`
+	To   interface{}         `json:"to,omitempty"`
+	// Added and Removed list the members of a set only in To and only in From respectively.
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	// Sensitive is set, and both values dropped, when the values must not be reported.
+	Sensitive bool `json:"sensitive,omitempty"`
+}
+
+// DiffOptions tells DiffAttributes which lists are unordered and which maps are compared key by
+// key. Both are named by schema path, the attribute path without list indexes or set keys, e.g.
+// root_block_device.volume_size. A declared set or map that one side lacks is compared as empty,
+// so its members are reported one by one rather than as a whole.
+type DiffOptions struct {
+	// Sets lists the unordered lists of scalars, e.g. vpc_security_group_ids. A set that differs is
+	// reported once, at its path, with the members added and removed.
+	Sets []string
+	// SetKeys maps unordered lists of nested blocks to the attribute identifying each block, e.g.
+	// ebs_block_device to device_name, so blocks are matched by that attribute rather than position.
+	SetKeys map[string]string
+	// Maps lists the maps whose keys are reported one by one, e.g. tags.
+	Maps []string
+}
+
+// DiffAttributes walks two attribute trees of maps, lists and scalar values, as decoded from JSON
+// or built from a resource's fields, and returns their differences in path order. Paths follow the
+// state's attribute paths: root_block_device[0].volume_size for list elements,
+// ebs_block_device["/dev/sdf"].volume_size for the blocks of a set, and tags.Name or
+// tags["kubernetes.io/cluster"] for map keys. Null values and empty maps and lists are treated as
+// absent, and numbers compare by value, so 8 and 8.0 are equal. Strings are compared as written,
+// even when they look like numbers, so a tag changed from "1.0" to "1" is reported.
+func DiffAttributes(from, to interface{}, opts DiffOptions) []AttributeChange {
+	d := attributeDiffer{opts: opts}
+	d.diff("", "", from, to)
+	sortChanges(d.changes)
+	return d.changes
+}
+
+// sortChanges orders changes by path.
+func sortChanges(changes []AttributeChange) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+}
+
+type attributeDiffer struct {
+	opts    DiffOptions
+	changes []AttributeChange
+}
+
+func (d *attributeDiffer) add(kind entities.ChangeKind, path string, from, to interface{}) {
+	d.changes = append(d.changes, AttributeChange{Path: path, Kind: kind, From: from, To: to})
+}
+
+// diff compares the values at path; schema is path without list indexes or set keys.
+func (d *attributeDiffer) diff(path, schema string, from, to interface{}) {
+	from, to = normalizeAttribute(from), normalizeAttribute(to)
+	switch {
+	case from == nil && d.expands(schema, to):
+		from = emptyLike(to)
+	case to == nil && d.expands(schema, from):
+		to = emptyLike(from)
+	}
+	switch {
+	case from == nil && to == nil:
+		return
+	case from == nil:
+		d.add(entities.ChangeAdded, path, nil, to)
+		return
+	case to == nil:
+		d.add(entities.ChangeRemoved, path, from, nil)
+		return
+	}
+
+	switch f := from.(type) {
+	case map[string]interface{}:
+		if t, ok := to.(map[string]interface{}); ok {
+			d.diffMap(path, schema, f, t)
+			return
+		}
+	case []interface{}:
+		if t, ok := to.([]interface{}); ok {
+			d.diffList(path, schema, f, t)
+			return
+		}
+	default:
+		if !isCollection(to) {
+			if !scalarEqual(from, to) {
+				d.add(entities.ChangeModified, path, from, to)
+			}
+			return
+		}
+	}
+	// A scalar replaced by a collection, or the other way round
+	d.add(entities.ChangeModified, path, from, to)
+}
+
+func (d *attributeDiffer) diffMap(path, schema string, from, to map[string]interface{}) {
+	keys := make([]string, 0, len(from)+len(to))
+	for key := range from {
+		keys = append(keys, key)
+	}
+	for key := range to {
+		if _, ok := from[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		d.diff(attributeKeyPath(path, key), schemaPath(schema, key), from[key], to[key])
+	}
+}
+
+func (d *attributeDiffer) diffList(path, schema string, from, to []interface{}) {
+	if key, ok := d.opts.SetKeys[schema]; ok {
+		d.diffSet(path, schema, from, to, func(element interface{}) string {
+			if block, ok := element.(map[string]interface{}); ok {
+				return scalarString(block[key])
+			}
+			return scalarString(element)
+		})
+		return
+	}
+	if contains(d.opts.Sets, schema) {
+		d.diffScalarSet(path, from, to)
+		return
+	}
+
+	for i := 0; i < len(from) || i < len(to); i++ {
+		var f, t interface{}
+		if i < len(from) {
+			f = from[i]
+		}
+		if i < len(to) {
+			t = to[i]
+		}
+		d.diff(fmt.Sprintf("%s[%d]", path, i), schema, f, t)
+	}
+}
+
+// diffSet matches the elements of two unordered lists by key and diffs each pair.
+func (d *attributeDiffer) diffSet(path, schema string, from, to []interface{}, key func(interface{}) string) {
+	fromByKey := make(map[string]interface{}, len(from))
+	for _, element := range from {
+		fromByKey[key(normalizeAttribute(element))] = element
+	}
+	toByKey := make(map[string]interface{}, len(to))
+	for _, element := range to {
+		toByKey[key(normalizeAttribute(element))] = element
+	}
+
+	keys := make([]string, 0, len(fromByKey)+len(toByKey))
+	for k := range fromByKey {
+		keys = append(keys, k)
+	}
+	for k := range toByKey {
+		if _, ok := fromByKey[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		d.diff(setElementPath(path, k), schema, fromByKey[k], toByKey[k])
+	}
+}
+
+// diffScalarSet compares two unordered lists by their members and reports them once if they differ.
+func (d *attributeDiffer) diffScalarSet(path string, from, to []interface{}) {
+	fromKeys, toKeys := setElementKeys(from), setElementKeys(to)
+	added := setDifference(toKeys, fromKeys)
+	removed := setDifference(fromKeys, toKeys)
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+	change := AttributeChange{Path: path, Kind: entities.ChangeModified, Added: added, Removed: removed}
+	if len(fromKeys) > 0 {
+		change.From = fromKeys
+	} else {
+		change.Kind = entities.ChangeAdded
+	}
+	if len(toKeys) > 0 {
+		change.To = toKeys
+	} else {
+		change.Kind = entities.ChangeRemoved
+	}
+	d.changes = append(d.changes, change)
+}
+
+// setElementKeys returns the keys of a set's elements, sorted and without duplicates.
+func setElementKeys(elements []interface{}) []string {
+	var keys []string
+	for _, element := range elements {
+		key := setElementKey(normalizeAttribute(element))
+		if !contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// expands reports whether v is a declared set or map, compared member by member even when the
+// other side lacks it. The blocks of a set share its schema path, so only the list expands.
+func (d *attributeDiffer) expands(schema string, v interface{}) bool {
+	switch v.(type) {
+	case []interface{}:
+		_, keyed := d.opts.SetKeys[schema]
+		return keyed || contains(d.opts.Sets, schema)
+	case map[string]interface{}:
+		return contains(d.opts.Maps, schema)
+	}
+	return false
+}
+
+// emptyLike returns an empty map or list of the same kind as v, or nil for a scalar.
+func emptyLike(v interface{}) interface{} {
+	switch v.(type) {
+	case map[string]interface{}:
+		return map[string]interface{}{}
+	case []interface{}:
+		return []interface{}{}
+	}
+	return nil
+}
+
+// setElementKey identifies a set element by its value, or by its JSON encoding for nested blocks.
+func setElementKey(element interface{}) string {
+	if isCollection(element) {
+		raw, _ := json.Marshal(element)
+		return string(raw)
+	}
+	return scalarString(element)
+}
+
+// setElementPath appends the key of a set element to path, e.g. ebs_block_device["/dev/sdf"].
+func setElementPath(path, key string) string {
+	return path + "[" + strconv.Quote(key) + "]"
+}
+
+// attributeKeyPath appends a map key to path, quoting keys that would be ambiguous after a dot,
+// e.g. tags["kubernetes.io/cluster"].
+func attributeKeyPath(path, key string) string {
+	if key == "" || strings.ContainsAny(key, ".[]\" ") {
+		return path + "[" + strconv.Quote(key) + "]"
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func schemaPath(schema, key string) string {
+	if schema == "" {
+		return key
+	}
+	return schema + "." + key
+}
+
+// normalizeAttribute turns null and empty collections into nil, and typed string maps and lists
+// into their generic form.
+func normalizeAttribute(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if len(t) == 0 {
+			return nil
+		}
+	case []interface{}:
+		if len(t) == 0 {
+			return nil
+		}
+	case map[string]string:
+		if len(t) == 0 {
+			return nil
+		}
+		m := make(map[string]interface{}, len(t))
+		for k, s := range t {
+			m[k] = s
+		}
+		return m
+	case []string:
+		if len(t) == 0 {
+			return nil
+		}
+		l := make([]interface{}, len(t))
+		for i, s := range t {
+			l[i] = s
+		}
+		return l
+	}
+	return v
+}
+
+func isCollection(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
+
+// scalarEqual compares two scalars by value. Numbers are equal when their values are, so 8 and 8.0
+// are equal, but a number never equals a string.
+func scalarEqual(a, b interface{}) bool {
+	x, aNumber := scalarNumber(a)
+	y, bNumber := scalarNumber(b)
+	if aNumber || bNumber {
+		return aNumber && bNumber && x == y
+	}
+	return scalarString(a) == scalarString(b)
+}
+
+// scalarNumber returns the value of a scalar decoded as a number. Strings are not parsed.
+func scalarNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func scalarString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// setDifference returns the members of a that b lacks, sorted and without duplicates.
+func setDifference(a, b []string) []string {
+	var result []string
+	for _, item := range a {
+		if !contains(b, item) && !contains(result, item) {
+			result = append(result, item)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
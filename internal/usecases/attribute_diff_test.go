@@ -0,0 +1,138 @@
+package usecases
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeTree(t *testing.T, raw string) interface{} {
+	t.Helper()
+	var tree interface{}
+	assert.NoError(t, json.Unmarshal([]byte(raw), &tree))
+	return tree
+}
+
+func TestDiffAttributes_NestedBlocks(t *testing.T) {
+	from := decodeTree(t, `{
+		"instance_type": "t3.micro",
+		"monitoring": false,
+		"tags": {"Name": "web", "Owner": "ops"},
+		"root_block_device": [{"volume_size": 8, "volume_type": "gp2"}],
+		"metadata_options": [{"http_tokens": "optional"}],
+		"user_data": null,
+		"secondary_private_ips": []
+	}`)
+	to := decodeTree(t, `{
+		"instance_type": "t3.micro",
+		"monitoring": true,
+		"tags": {"Name": "web", "Team": "platform", "kubernetes.io/cluster": "owned"},
+		"root_block_device": [{"volume_size": 20, "volume_type": "gp2"}, {"volume_size": 8}],
+		"user_data": "",
+		"secondary_private_ips": null
+	}`)
+
+	changes := DiffAttributes(from, to, DiffOptions{})
+	assert.Equal(t, []AttributeChange{
+		{Path: "metadata_options", Kind: entities.ChangeRemoved, From: []interface{}{map[string]interface{}{"http_tokens": "optional"}}},
+		{Path: "monitoring", Kind: entities.ChangeModified, From: false, To: true},
+		{Path: "root_block_device[0].volume_size", Kind: entities.ChangeModified, From: float64(8), To: float64(20)},
+		{Path: "root_block_device[1]", Kind: entities.ChangeAdded, To: map[string]interface{}{"volume_size": float64(8)}},
+		{Path: "tags.Owner", Kind: entities.ChangeRemoved, From: "ops"},
+		{Path: "tags.Team", Kind: entities.ChangeAdded, To: "platform"},
+		{Path: `tags["kubernetes.io/cluster"]`, Kind: entities.ChangeAdded, To: "owned"},
+		{Path: "user_data", Kind: entities.ChangeAdded, To: ""},
+	}, changes)
+}
+
+func TestDiffAttributes_Sets(t *testing.T) {
+	from := decodeTree(t, `{
+		"vpc_security_group_ids": ["sg-1", "sg-2"],
+		"ebs_block_device": [
+			{"device_name": "/dev/sdf", "volume_size": 10},
+			{"device_name": "/dev/sdg", "volume_size": 50}
+		]
+	}`)
+	to := decodeTree(t, `{
+		"vpc_security_group_ids": ["sg-3", "sg-1"],
+		"ebs_block_device": [
+			{"device_name": "/dev/sdh", "volume_size": 5},
+			{"device_name": "/dev/sdf", "volume_size": 100}
+		]
+	}`)
+	opts := DiffOptions{
+		Sets:    []string{"vpc_security_group_ids"},
+		SetKeys: map[string]string{"ebs_block_device": "device_name"},
+	}
+
+	changes := DiffAttributes(from, to, opts)
+	assert.Equal(t, []AttributeChange{
+		{Path: `ebs_block_device["/dev/sdf"].volume_size`, Kind: entities.ChangeModified, From: float64(10), To: float64(100)},
+		{Path: `ebs_block_device["/dev/sdg"]`, Kind: entities.ChangeRemoved, From: map[string]interface{}{"device_name": "/dev/sdg", "volume_size": float64(50)}},
+		{Path: `ebs_block_device["/dev/sdh"]`, Kind: entities.ChangeAdded, To: map[string]interface{}{"device_name": "/dev/sdh", "volume_size": float64(5)}},
+		{
+			Path: "vpc_security_group_ids", Kind: entities.ChangeModified,
+			From: []string{"sg-1", "sg-2"}, To: []string{"sg-1", "sg-3"},
+			Added: []string{"sg-3"}, Removed: []string{"sg-2"},
+		},
+	}, changes)
+
+	// Without the set options the same lists are compared by position
+	assert.Len(t, DiffAttributes(from, to, DiffOptions{}), 6)
+}
+
+func TestDiffAttributes_ScalarsAndTypedValues(t *testing.T) {
+	assert.Empty(t, DiffAttributes(map[string]interface{}{"volume_size": 8}, map[string]interface{}{"volume_size": 8.0}, DiffOptions{}))
+	assert.Empty(t, DiffAttributes(json.Number("8"), 8.0, DiffOptions{}))
+	// Strings are not read as numbers
+	assert.Equal(t, []AttributeChange{{Path: "volume_size", Kind: entities.ChangeModified, From: "8", To: 8}},
+		DiffAttributes(map[string]interface{}{"volume_size": "8"}, map[string]interface{}{"volume_size": 8}, DiffOptions{}))
+	assert.Equal(t, []AttributeChange{
+		{Path: "tags.Build", Kind: entities.ChangeModified, From: "007", To: "7"},
+		{Path: "tags.Limit", Kind: entities.ChangeModified, From: "inf", To: "Infinity"},
+		{Path: "tags.Version", Kind: entities.ChangeModified, From: "1.0", To: "1"},
+	}, DiffAttributes(
+		map[string]interface{}{"tags": map[string]string{"Version": "1.0", "Build": "007", "Limit": "inf"}},
+		map[string]interface{}{"tags": map[string]string{"Version": "1", "Build": "7", "Limit": "Infinity"}},
+		DiffOptions{},
+	))
+	assert.Empty(t, DiffAttributes(map[string]string{}, nil, DiffOptions{}))
+	assert.Equal(t, []AttributeChange{{Path: "[0]", Kind: entities.ChangeModified, From: "a", To: "b"}},
+		DiffAttributes([]string{"a"}, []string{"b"}, DiffOptions{}))
+	assert.Equal(t, []AttributeChange{{Path: "tags", Kind: entities.ChangeModified, From: "none", To: map[string]interface{}{"Name": "web"}}},
+		DiffAttributes(map[string]interface{}{"tags": "none"}, map[string]interface{}{"tags": map[string]string{"Name": "web"}}, DiffOptions{}))
+}
+
+func TestDiffAttributes_DeclaredSetsAndMapsMissingOnOneSide(t *testing.T) {
+	from := map[string]interface{}{"instance_type": "t3.micro"}
+	to := map[string]interface{}{
+		"instance_type":          "t3.micro",
+		"tags":                   map[string]string{"Name": "web"},
+		"vpc_security_group_ids": []string{"sg-1"},
+		"ebs_block_device":       []interface{}{map[string]interface{}{"device_name": "/dev/sdf", "volume_size": 10}},
+	}
+	opts := DiffOptions{
+		Sets:    []string{"vpc_security_group_ids"},
+		SetKeys: map[string]string{"ebs_block_device": "device_name"},
+		Maps:    []string{"tags"},
+	}
+
+	assert.Equal(t, []AttributeChange{
+		{Path: `ebs_block_device["/dev/sdf"]`, Kind: entities.ChangeAdded, To: map[string]interface{}{"device_name": "/dev/sdf", "volume_size": 10}},
+		{Path: "tags.Name", Kind: entities.ChangeAdded, To: "web"},
+		{Path: "vpc_security_group_ids", Kind: entities.ChangeAdded, To: []string{"sg-1"}, Added: []string{"sg-1"}},
+	}, DiffAttributes(from, to, opts))
+
+	// Undeclared, the same attributes are added as a whole
+	assert.Equal(t, []string{"ebs_block_device", "tags", "vpc_security_group_ids"}, changePaths(DiffAttributes(from, to, DiffOptions{})))
+}
+
+func changePaths(changes []AttributeChange) []string {
+	var paths []string
+	for _, change := range changes {
+		paths = append(paths, change.Path)
+	}
+	return paths
+}
@@ -3,8 +3,10 @@ package usecases
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/cstudio7/drift-detector/internal/domain/entities"
 	"github.com/cstudio7/drift-detector/internal/interfaces/aws"
@@ -13,17 +15,97 @@ import (
 )
 
 type DriftDetector struct {
-	awsClient aws.AWSClient
-	tfParser  terraform.TFStateParser
-	logger    logger.Logger
+	awsClient     aws.AWSClient
+	tfParser      terraform.TFStateParser
+	logger        logger.Logger
+	addressPrefix string
+	stateLabels   map[string]string
+	ignoredTags   globMatcher
+	suppressions  *Suppressions
+	severity      *SeverityPolicy
 }
 
-func NewDriftDetector(awsClient aws.AWSClient, logger logger.Logger) *DriftDetector {
-	return &DriftDetector{
-		awsClient: awsClient,
-		tfParser:  terraform.NewTFStateParser(logger),
-		logger:    logger,
+// Option configures a DriftDetector.
+type Option func(*DriftDetector)
+
+// WithTFParser replaces the default Terraform state parser.
+func WithTFParser(tfParser terraform.TFStateParser) Option {
+	return func(d *DriftDetector) {
+		d.tfParser = tfParser
+	}
+}
+
+// WithAddressPrefix limits detection to Terraform resources under the given address, e.g. module.app.
+func WithAddressPrefix(prefix string) Option {
+	return func(d *DriftDetector) {
+		d.addressPrefix = prefix
+	}
+}
+
+// WithStateLabels names states in reports by label instead of by path, e.g. a discovered
+// state's root module and workspace.
+func WithStateLabels(labels map[string]string) Option {
+	return func(d *DriftDetector) {
+		d.stateLabels = labels
+	}
+}
+
+// WithIgnoredTags replaces DefaultIgnoredTags with the given tag key patterns, e.g. aws:* or
+// kubernetes.io/*. Drift on matching tags is not reported.
+func WithIgnoredTags(patterns []string) Option {
+	return func(d *DriftDetector) {
+		d.ignoredTags = newGlobMatcher(patterns)
+	}
+}
+
+// WithSuppressions accepts the drift matched by the given rules, e.g. as read by LoadSuppressions.
+// Suppressed drift is counted but not reported as changes.
+func WithSuppressions(suppressions *Suppressions) Option {
+	return func(d *DriftDetector) {
+		d.suppressions = suppressions
+	}
+}
+
+// WithSeverityPolicy replaces DefaultSeverityPolicy, e.g. with one read by LoadSeverityPolicy.
+func WithSeverityPolicy(policy *SeverityPolicy) Option {
+	return func(d *DriftDetector) {
+		d.severity = policy
+	}
+}
+
+func NewDriftDetector(awsClient aws.AWSClient, logger logger.Logger, opts ...Option) *DriftDetector {
+	d := &DriftDetector{
+		awsClient:   awsClient,
+		tfParser:    terraform.NewTFStateParser(logger),
+		logger:      logger,
+		ignoredTags: newGlobMatcher(DefaultIgnoredTags),
+		severity:    DefaultSeverityPolicy(),
+	}
+	for _, opt := range opts {
+		opt(d)
 	}
+	return d
+}
+
+// stateVersions returns the states a run compared against. Parsers that read no version, such
+// as the show -json parser, report no states; those are listed by label alone.
+func (d *DriftDetector) stateVersions(tfStateFiles []string, states []terraform.StateVersion) []entities.StateVersion {
+	versions := make([]entities.StateVersion, 0, len(tfStateFiles))
+	if len(states) == 0 {
+		for _, file := range tfStateFiles {
+			versions = append(versions, entities.StateVersion{Source: d.stateLabel(file)})
+		}
+		return versions
+	}
+	for _, state := range states {
+		versions = append(versions, entities.StateVersion{
+			Source:           state.Source,
+			Serial:           state.Serial,
+			Lineage:          state.Lineage,
+			TerraformVersion: state.TerraformVersion,
+		})
+	}
+	return versions
 }
 
 // Getter methods for testing
@@ -31,45 +113,135 @@ func (d *DriftDetector) AWSClient() aws.AWSClient          { return d.awsClient
 func (d *DriftDetector) TFParser() terraform.TFStateParser { return d.tfParser }
 func (d *DriftDetector) Logger() logger.Logger             { return d.logger }
 
-func (d *DriftDetector) DetectDrift(tfStateFile string) error {
+// DetectDrift compares live EC2 instances with the desired state merged from one or more state files
+// and returns a report for every compared, unmanaged and missing instance. When some instances cannot
+// be compared, the result still holds the reports of the others alongside the error.
+func (d *DriftDetector) DetectDrift(tfStateFiles ...string) (*entities.DriftResult, error) {
+	run := &entities.DriftResult{StartTime: time.Now()}
+	if r, ok := d.awsClient.(regionReporter); ok {
+		run.Region = r.Region()
+	}
 	awsConfigs, err := d.awsClient.FetchInstanceConfigs()
 	if err != nil {
-		return fmt.Errorf("%w: %v", entities.ErrFetchAWSConfigs, err)
+		return nil, fmt.Errorf("%w: %v", entities.ErrFetchAWSConfigs, err)
 	}
 	if len(awsConfigs) == 0 {
 		d.logger.Warn("No AWS configurations found")
-		return entities.ErrEmptyConfigs
+		return nil, entities.ErrEmptyConfigs
 	}
 	d.logger.Info("Fetched AWS configs", "count", len(awsConfigs))
 
-	tfConfigs, err := d.tfParser.ParseTFState(tfStateFile)
+	tfConfigs, conflicts, err := d.parseStates(tfStateFiles)
 	if err != nil {
-		return fmt.Errorf("%w: %v", entities.ErrInvalidTerraformState, err)
+		return nil, fmt.Errorf("%w: %v", entities.ErrInvalidTerraformState, err)
 	}
 	if tfConfigs.IsEmpty() {
 		d.logger.Warn("No Terraform configurations found")
-		return entities.ErrEmptyConfigs
+		return nil, entities.ErrEmptyConfigs
+	}
+	d.logger.Info("Parsed Terraform configs", "states", len(tfStateFiles), "instance_types", tfConfigs.InstanceTypes)
+	for _, state := range tfConfigs.States {
+		d.logger.Info("State version", "source", state.Source, "serial", state.Serial, "lineage", state.Lineage)
+	}
+	run.States = d.stateVersions(tfStateFiles, tfConfigs.States)
+	for _, stale := range findStaleStates(tfConfigs.States) {
+		d.logger.Warn(formatStale(stale))
+	}
+	for _, conflict := range conflicts {
+		d.logger.Warn(formatConflict(conflict))
+	}
+
+	paired, unmanaged, missing := classifyInstances(awsConfigs, tfConfigs)
+	if d.addressPrefix != "" {
+		if len(tfConfigs.Instances) == 0 {
+			d.logger.Warn("Address prefix ignored: state has no resource addresses", "address_prefix", d.addressPrefix)
+		} else {
+			paired, missing = filterByAddressPrefix(paired, missing, tfConfigs, d.addressPrefix)
+			// Unmanaged instances belong to no address, so they cannot fall under the prefix
+			unmanaged = nil
+			d.logger.Info("Filtered by address prefix", "address_prefix", d.addressPrefix, "instances", len(paired)+len(missing))
+		}
+	}
+	rules := d.suppressions.active(run.StartTime, d.logger)
+	// Reports are logged once all are in, in report order, since comparisons finish in any order
+	var findings []finding
+	for _, config := range unmanaged {
+		report := entities.DriftReport{InstanceID: config.InstanceID, Kind: entities.DriftUnmanaged, HasDrift: true}
+		var message string
+		if justification, ok := suppressInstance(rules, suppressionTarget{instanceID: config.InstanceID, tags: config.Tags}); ok {
+			report.HasDrift = false
+			report.Suppressed = map[string]string{string(entities.DriftUnmanaged): justification}
+			run.Counts.Suppressed++
+		} else {
+			report.Severity = d.severity.Unmanaged
+			message = formatUnmanaged(config)
+			run.Counts.Unmanaged++
+		}
+		findings = append(findings, finding{report: report, message: message})
+	}
+	for _, m := range missing {
+		report := entities.DriftReport{
+			InstanceID: m.resource.Config.InstanceID,
+			Address:    m.resource.Address(),
+			Source:     m.resource.Source,
+			Kind:       entities.DriftMissing,
+			HasDrift:   true,
+		}
+		var message string
+		target := suppressionTarget{address: report.Address, instanceID: report.InstanceID, tags: m.resource.Config.Tags}
+		if justification, ok := suppressInstance(rules, target); ok {
+			report.HasDrift = false
+			report.Suppressed = map[string]string{string(entities.DriftMissing): justification}
+			run.Counts.Suppressed++
+		} else {
+			report.Severity = d.severity.Missing
+			message = formatMissing(m)
+			run.Counts.Missing++
+		}
+		findings = append(findings, finding{report: report, message: message})
 	}
-	d.logger.Info("Parsed Terraform configs", "instance_types", tfConfigs.InstanceTypes)
 
 	type driftResult struct {
 		instanceID string
-		diff       map[string]map[string]string
+		resource   *terraform.InstanceResource
+		changes    []AttributeChange
+		suppressed map[string]string
 		err        error
 	}
-	results := make(chan driftResult, len(awsConfigs))
+	results := make(chan driftResult, len(paired))
+
+	// Equivalent representations are canonicalised before comparing, e.g. AWS returns the IAM
+	// instance profile's ARN where the state holds its name
+	var lookups entities.Lookups
+	if len(paired) > 0 {
+		lookups = d.lookups()
+		tfConfigs = normalizeConfigSet(tfConfigs, lookups)
+	}
 
 	var wg sync.WaitGroup
-	for _, awsConfig := range awsConfigs {
+	for _, awsConfig := range paired {
 		wg.Add(1)
 		go func(config entities.InstanceConfig) {
 			defer wg.Done()
-			diff, err := compareConfigs(config, tfConfigs)
-			results <- driftResult{
-				instanceID: config.InstanceID,
-				diff:       diff,
-				err:        err,
+			result := driftResult{instanceID: config.InstanceID}
+			config = normalizeConfig(config, lookups)
+			// Pair with the resource that claims this instance ID when the state has per-instance data
+			if resource, ok := tfConfigs.Instances[config.InstanceID]; ok {
+				resource.Config = normalizeConfig(resource.Config, lookups)
+				result.resource = &resource
+				result.changes, result.err = compareInstance(config, resource.Config)
+				result.changes = dropUnknown(result.changes, resource)
+				redactSensitive(result.changes, resource)
+			} else {
+				result.changes, result.err = compareConfigs(config, tfConfigs)
 			}
+			result.changes = dropIgnoredTags(result.changes, d.ignoredTags)
+			target := suppressionTarget{instanceID: config.InstanceID, tags: config.Tags}
+			if result.resource != nil {
+				target.address = result.resource.Address()
+			}
+			result.changes, result.suppressed = suppressFields(rules, target, result.changes)
+			results <- result
 		}(awsConfig)
 	}
 
@@ -84,69 +256,385 @@ func (d *DriftDetector) DetectDrift(tfStateFile string) error {
 			errs = append(errs, fmt.Errorf("instance %s: %w", result.instanceID, result.err))
 			continue
 		}
-		if len(result.diff) > 0 {
-			d.logger.Info(formatDrift(result.instanceID, result.diff))
+		report := entities.DriftReport{InstanceID: result.instanceID, HasDrift: len(result.changes) > 0, Suppressed: result.suppressed}
+		run.Counts.Suppressed += len(result.suppressed)
+		var label, message string
+		if result.resource != nil {
+			report.Address = result.resource.Address()
+			report.Source = result.resource.Source
+			label = resourceLabel(*result.resource)
+		}
+		if report.HasDrift {
+			report.Kind = entities.DriftModified
+			report.Changes = toChanges(result.changes)
+			report.Severity = d.severity.classifyChanges(report.Changes)
+			run.Counts.Modified++
+			message = formatDrift(result.instanceID, label, result.changes)
+		}
+		findings = append(findings, finding{report: report, message: message})
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		return reportLess(findings[i].report, findings[j].report)
+	})
+	for _, f := range findings {
+		if f.message != "" {
+			d.logger.Info(f.message, "severity", f.report.Severity)
 		}
+		run.Reports = append(run.Reports, f.report)
 	}
+	run.Counts.Compared = len(paired) - len(errs)
+	run.Counts.Conflicts = len(conflicts)
+	scoreRun(run)
+	run.EndTime = time.Now()
+	d.logger.Info("Drift summary", "modified", run.Counts.Modified, "unmanaged", run.Counts.Unmanaged, "missing", run.Counts.Missing, "conflicts", run.Counts.Conflicts, "suppressed", run.Counts.Suppressed, "risk_score", run.RiskScore, "max_severity", run.MaxSeverity)
 
 	if len(errs) > 0 {
-		return fmt.Errorf("%w: %v", entities.ErrConfigComparison, errors.Join(errs...))
+		return run, fmt.Errorf("%w: %v", entities.ErrConfigComparison, errors.Join(errs...))
+	}
+
+	return run, nil
+}
+
+// finding is a report with the message logged for it, if any.
+type finding struct {
+	report  entities.DriftReport
+	message string
+}
+
+// reportLess orders reports by resource address, then instance ID. Unmanaged instances have no
+// address and come first.
+func reportLess(a, b entities.DriftReport) bool {
+	if a.Address != b.Address {
+		return a.Address < b.Address
+	}
+	return a.InstanceID < b.InstanceID
+}
+
+// regionReporter is implemented by AWS clients that know the region they read instances from.
+type regionReporter interface {
+	Region() string
+}
+
+// lookupsProvider is implemented by AWS clients that can resolve the alternative identifiers
+// Terraform may use, such as security group names.
+type lookupsProvider interface {
+	Lookups() (entities.Lookups, error)
+}
+
+// lookups returns the AWS client's lookups, or none when it cannot provide them. Without lookups,
+// only values that carry their canonical form, such as ARNs, are normalised.
+func (d *DriftDetector) lookups() entities.Lookups {
+	provider, ok := d.awsClient.(lookupsProvider)
+	if !ok {
+		return entities.Lookups{}
 	}
+	lookups, err := provider.Lookups()
+	if err != nil {
+		d.logger.Warn("Security group names and availability zone IDs will not be resolved", "error", err.Error())
+		return entities.Lookups{}
+	}
+	return lookups
+}
 
-	return nil
+// toChanges converts attribute changes into report changes, keyed by path.
+func toChanges(changes []AttributeChange) map[string]entities.Change {
+	result := make(map[string]entities.Change, len(changes))
+	for _, change := range changes {
+		if change.Sensitive {
+			result[change.Path] = entities.Change{Kind: change.Kind, Sensitive: true}
+			continue
+		}
+		result[change.Path] = entities.Change{
+			Expected: change.From,
+			Actual:   change.To,
+			Kind:     change.Kind,
+			Attached: change.Added,
+			Detached: change.Removed,
+		}
+	}
+	return result
 }
 
-func compareConfigs(awsConfig entities.InstanceConfig, tfConfigs terraform.InstanceConfigSet) (map[string]map[string]string, error) {
+// compareConfigs compares a live instance with the aggregated lists of a legacy state, which only
+// tell which values some resource holds. Each change's From is that list.
+func compareConfigs(awsConfig entities.InstanceConfig, tfConfigs terraform.InstanceConfigSet) ([]AttributeChange, error) {
 	if awsConfig.InstanceID == "" {
 		return nil, fmt.Errorf("%w: empty instance ID", entities.ErrConfigComparison)
 	}
 
-	diff := make(map[string]map[string]string)
-
-	if !contains(tfConfigs.InstanceTypes, awsConfig.InstanceType) {
-		diff["instance_type"] = map[string]string{"aws": awsConfig.InstanceType, "tf": strings.Join(tfConfigs.InstanceTypes, ", ")}
+	var changes []AttributeChange
+	notListed := func(path string, allowed []string, value string) {
+		if !contains(allowed, value) {
+			changes = append(changes, AttributeChange{Path: path, Kind: entities.ChangeModified, From: allowed, To: value})
+		}
 	}
+
+	notListed("instance_type", tfConfigs.InstanceTypes, awsConfig.InstanceType)
 	if !allIn(awsConfig.SecurityGroupIDs, tfConfigs.SecurityGroupIDs) {
-		diff["security_group_ids"] = map[string]string{"aws": strings.Join(awsConfig.SecurityGroupIDs, ", "), "tf": strings.Join(tfConfigs.SecurityGroupIDs, ", ")}
+		// The aggregated list cannot tell which groups an instance should have, only which it should not
+		changes = append(changes, AttributeChange{
+			Path:  "vpc_security_group_ids",
+			Kind:  entities.ChangeModified,
+			From:  tfConfigs.SecurityGroupIDs,
+			To:    awsConfig.SecurityGroupIDs,
+			Added: setDifference(awsConfig.SecurityGroupIDs, tfConfigs.SecurityGroupIDs),
+		})
 	}
-	if !contains(tfConfigs.SubnetIDs, awsConfig.SubnetID) {
-		diff["subnet_id"] = map[string]string{"aws": awsConfig.SubnetID, "tf": strings.Join(tfConfigs.SubnetIDs, ", ")}
+	notListed("subnet_id", tfConfigs.SubnetIDs, awsConfig.SubnetID)
+	notListed("iam_instance_profile", tfConfigs.IAMInstanceProfiles, awsConfig.IAMInstanceProfile)
+	// Older aggregated states may not list these at all, which is not drift
+	if len(tfConfigs.AMIs) > 0 {
+		notListed("ami", tfConfigs.AMIs, awsConfig.AMI)
 	}
-	if !contains(tfConfigs.IAMInstanceProfiles, awsConfig.IAMInstanceProfile) {
-		diff["iam_instance_profile"] = map[string]string{"aws": awsConfig.IAMInstanceProfile, "tf": strings.Join(tfConfigs.IAMInstanceProfiles, ", ")}
+	if len(tfConfigs.AvailabilityZones) > 0 {
+		notListed("availability_zone", tfConfigs.AvailabilityZones, awsConfig.AvailabilityZone)
 	}
-	if awsConfig.Tags != nil {
-		if name, ok := awsConfig.Tags["Name"]; ok && !contains(tfConfigs.TagNames, name) {
-			diff["tag.Name"] = map[string]string{"aws": name, "tf": strings.Join(tfConfigs.TagNames, ", ")}
-		}
-		if env, ok := awsConfig.Tags["Environment"]; ok && !contains(tfConfigs.TagEnvironments, env) {
-			diff["tag.Environment"] = map[string]string{"aws": env, "tf": strings.Join(tfConfigs.TagEnvironments, ", ")}
-		}
+	if len(tfConfigs.KeyNames) > 0 {
+		notListed("key_name", tfConfigs.KeyNames, awsConfig.KeyName)
+	}
+	if name, ok := awsConfig.Tags["Name"]; ok {
+		notListed("tags.Name", tfConfigs.TagNames, name)
+	}
+	if env, ok := awsConfig.Tags["Environment"]; ok {
+		notListed("tags.Environment", tfConfigs.TagEnvironments, env)
 	}
 	for _, ebs := range awsConfig.EBSBlockDevices {
 		if ebs.VolumeSize <= 0 {
 			return nil, fmt.Errorf("%w: invalid EBS volume size %d", entities.ErrConfigComparison, ebs.VolumeSize)
 		}
+		device := setElementPath("ebs_block_device", ebs.DeviceName)
 		if !containsInt(tfConfigs.EBSVolumeSizes, ebs.VolumeSize) {
-			diff["ebs_volume_size"] = map[string]string{"aws": fmt.Sprintf("%d", ebs.VolumeSize), "tf": joinInt(tfConfigs.EBSVolumeSizes)}
+			changes = append(changes, AttributeChange{Path: device + ".volume_size", Kind: entities.ChangeModified, From: tfConfigs.EBSVolumeSizes, To: ebs.VolumeSize})
+		}
+		notListed(device+".volume_type", tfConfigs.EBSVolumeTypes, ebs.VolumeType)
+	}
+
+	sortChanges(changes)
+	return changes, nil
+}
+
+// missingInstance is a Terraform-managed instance that AWS no longer runs.
+type missingInstance struct {
+	resource terraform.InstanceResource
+	// state is the EC2 state AWS still reports, or empty when DescribeInstances did not return it.
+	state string
+}
+
+// classifyInstances splits live instances into those to compare and those no Terraform resource claims,
+// and collects the Terraform resources whose instance is gone. Legacy states carry no instance IDs,
+// so every live instance is compared against the aggregated lists instead.
+func classifyInstances(awsConfigs []entities.InstanceConfig, tfConfigs terraform.InstanceConfigSet) (paired, unmanaged []entities.InstanceConfig, missing []missingInstance) {
+	if len(tfConfigs.Instances) == 0 {
+		return awsConfigs, nil, nil
+	}
+
+	live := make(map[string]entities.InstanceConfig, len(awsConfigs))
+	for _, config := range awsConfigs {
+		live[config.InstanceID] = config
+		if config.IsTerminated() {
+			continue
+		}
+		if _, ok := tfConfigs.Instances[config.InstanceID]; ok {
+			paired = append(paired, config)
+		} else {
+			unmanaged = append(unmanaged, config)
+		}
+	}
+
+	for id, resource := range tfConfigs.Instances {
+		config, ok := live[id]
+		if ok && !config.IsTerminated() {
+			continue
 		}
-		if !contains(tfConfigs.EBSVolumeTypes, ebs.VolumeType) {
-			diff["ebs_volume_type"] = map[string]string{"aws": ebs.VolumeType, "tf": strings.Join(tfConfigs.EBSVolumeTypes, ", ")}
+		missing = append(missing, missingInstance{resource: resource, state: config.State})
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].resource.Config.InstanceID < missing[j].resource.Config.InstanceID
+	})
+
+	return paired, unmanaged, missing
+}
+
+// filterByAddressPrefix keeps only the paired and missing instances whose Terraform address falls under prefix.
+func filterByAddressPrefix(paired []entities.InstanceConfig, missing []missingInstance, tfConfigs terraform.InstanceConfigSet, prefix string) ([]entities.InstanceConfig, []missingInstance) {
+	var keptPaired []entities.InstanceConfig
+	for _, config := range paired {
+		if tfConfigs.Instances[config.InstanceID].MatchesAddressPrefix(prefix) {
+			keptPaired = append(keptPaired, config)
 		}
 	}
+	var keptMissing []missingInstance
+	for _, m := range missing {
+		if m.resource.MatchesAddressPrefix(prefix) {
+			keptMissing = append(keptMissing, m)
+		}
+	}
+	return keptPaired, keptMissing
+}
 
-	return diff, nil
+// compareInstance compares a live instance attribute-by-attribute with the Terraform resource that
+// manages it, from the Terraform value to the live one.
+func compareInstance(awsConfig, tfConfig entities.InstanceConfig) ([]AttributeChange, error) {
+	if awsConfig.InstanceID == "" {
+		return nil, fmt.Errorf("%w: empty instance ID", entities.ErrConfigComparison)
+	}
+	for _, device := range awsConfig.EBSBlockDevices {
+		if device.VolumeSize <= 0 {
+			return nil, fmt.Errorf("%w: invalid EBS volume size %d", entities.ErrConfigComparison, device.VolumeSize)
+		}
+	}
+	return diffInstances(tfConfig, awsConfig), nil
 }
 
-func formatDrift(instanceID string, diff map[string]map[string]string) string {
+func formatDrift(instanceID, address string, changes []AttributeChange) string {
 	var b strings.Builder
-	b.WriteString(fmt.Sprintf("Drift detected for instance %s:\n", instanceID))
-	for field, values := range diff {
-		b.WriteString(fmt.Sprintf("  - %s: AWS=%s, Terraform=%s\n", field, values["aws"], values["tf"]))
+	if address != "" {
+		b.WriteString(fmt.Sprintf("Drift detected for instance %s (%s):\n", instanceID, address))
+	} else {
+		b.WriteString(fmt.Sprintf("Drift detected for instance %s:\n", instanceID))
+	}
+	for _, change := range changes {
+		switch {
+		case change.Sensitive:
+			b.WriteString(fmt.Sprintf("  - %s: %s\n", change.Path, sensitiveValueChanged))
+		case len(change.Added) > 0 || len(change.Removed) > 0:
+			b.WriteString(fmt.Sprintf("  - %s: %s\n", change.Path, formatSetChange(change)))
+		default:
+			b.WriteString(fmt.Sprintf("  - %s: AWS=%s, Terraform=%s\n", change.Path, formatValue(change.To), formatValue(change.From)))
+		}
 	}
 	return b.String()
 }
 
+// formatSetChange lists the members of a set attached and detached outside Terraform, e.g.
+// "attached sg-3, detached sg-1, sg-2".
+func formatSetChange(change AttributeChange) string {
+	var parts []string
+	if len(change.Added) > 0 {
+		parts = append(parts, "attached "+strings.Join(change.Added, ", "))
+	}
+	if len(change.Removed) > 0 {
+		parts = append(parts, "detached "+strings.Join(change.Removed, ", "))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatValue renders an attribute value: lists comma-separated, blocks as their attributes in
+// name order, e.g. "device_name=/dev/sdf, volume_size=100", and an absent value as not set.
+func formatValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return notSet
+	case []string:
+		return strings.Join(t, ", ")
+	case []int:
+		return joinInt(t)
+	case []interface{}:
+		values := make([]string, len(t))
+		for i, element := range t {
+			values[i] = formatValue(element)
+		}
+		return strings.Join(values, ", ")
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for key := range t {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		values := make([]string, len(keys))
+		for i, key := range keys {
+			values[i] = key + "=" + formatValue(t[key])
+		}
+		return strings.Join(values, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// notSet is reported in place of the value of an attribute, such as a tag, set on one side only.
+const notSet = "(not set)"
+
+// sensitiveValueChanged is reported in place of the values of a sensitive attribute that drifted.
+const sensitiveValueChanged = "(sensitive value changed)"
+
+// redactSensitive drops both values of every change read from an attribute the state marks as
+// sensitive, so neither is logged or reported.
+func redactSensitive(changes []AttributeChange, resource terraform.InstanceResource) {
+	if len(resource.Sensitive) == 0 {
+		return
+	}
+	for i, change := range changes {
+		for _, attribute := range stateAttributes(change.Path) {
+			if resource.IsSensitive(attribute) {
+				changes[i] = AttributeChange{Path: change.Path, Kind: change.Kind, Sensitive: true}
+				break
+			}
+		}
+	}
+}
+
+// dropUnknown removes the changes whose desired value is unknown, e.g. set from a variable in HCL
+// configuration, since there is nothing to compare the live value against.
+func dropUnknown(changes []AttributeChange, resource terraform.InstanceResource) []AttributeChange {
+	if len(resource.Unknown) == 0 {
+		return changes
+	}
+	var kept []AttributeChange
+	for _, change := range changes {
+		unknown := false
+		for _, attribute := range stateAttributes(change.Path) {
+			if resource.IsUnknown(attribute) {
+				unknown = true
+				break
+			}
+		}
+		if !unknown {
+			kept = append(kept, change)
+		}
+	}
+	return kept
+}
+
+// stateAttributes maps the path of a change to the state attributes it is read from. Tags are
+// keyed as in sensitive and unknown paths, tags.<key>. Additional EBS volumes are keyed by device
+// name, as in unknown paths, but by list index in sensitive ones, so ebs_block_device as a whole
+// stands for those.
+func stateAttributes(path string) []string {
+	if key, ok := tagKey(path); ok {
+		return []string{"tags." + key, "tags_all." + key}
+	}
+	switch {
+	case path == "vpc_security_group_ids":
+		return []string{"vpc_security_group_ids", "security_groups"}
+	case strings.HasPrefix(path, "ebs_block_device["):
+		return []string{path, "ebs_block_device"}
+	default:
+		return []string{path}
+	}
+}
+
+func formatUnmanaged(config entities.InstanceConfig) string {
+	return fmt.Sprintf("Drift detected for instance %s: %s, no Terraform resource claims this ID (instance_type=%s, subnet_id=%s, tags.Name=%s)\n",
+		config.InstanceID, entities.DriftUnmanaged, config.InstanceType, config.SubnetID, config.Tags["Name"])
+}
+
+func formatMissing(m missingInstance) string {
+	reason := "not returned by AWS"
+	if m.state != "" {
+		reason = "instance is " + m.state + " in AWS"
+	}
+	return fmt.Sprintf("Drift detected for instance %s (%s): %s, declared in Terraform state but %s\n",
+		m.resource.Config.InstanceID, resourceLabel(m.resource), entities.DriftMissing, reason)
+}
+
+// resourceLabel is the resource address, followed by its state when it was merged from several.
+func resourceLabel(r terraform.InstanceResource) string {
+	if r.Source == "" {
+		return r.Address()
+	}
+	return r.Address() + " in " + r.Source
+}
+
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if s == item {
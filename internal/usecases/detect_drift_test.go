@@ -2,6 +2,8 @@ package usecases
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/cstudio7/drift-detector/internal/domain/entities"
@@ -21,6 +23,14 @@ func (m *mockAWSClient) FetchInstanceConfigs() ([]entities.InstanceConfig, error
 	return m.fetchConfigs()
 }
 
+// mockRegionAWSClient is a mockAWSClient that reports its region.
+type mockRegionAWSClient struct {
+	mockAWSClient
+	region string
+}
+
+func (m *mockRegionAWSClient) Region() string { return m.region }
+
 type mockTFStateParser struct {
 	parseFunc func(string) (terraform.InstanceConfigSet, error)
 }
@@ -38,9 +48,7 @@ func (m *mockLogger) Error(msg string, keysAndValues ...interface{}) {}
 // Helpers
 
 func newDriftDetector(awsClient aws.AWSClient, tfParser terraform.TFStateParser, logger logger.Logger) *DriftDetector {
-	detector := NewDriftDetector(awsClient, logger)
-	detector.tfParser = tfParser
-	return detector
+	return NewDriftDetector(awsClient, logger, WithTFParser(tfParser))
 }
 
 // Tests
@@ -95,7 +103,7 @@ func TestDetectDrift_Success_NoDrift(t *testing.T) {
 	logger := &mockLogger{}
 	detector := newDriftDetector(mockAWS, mockTF, logger)
 
-	err := detector.DetectDrift("mock.tfstate")
+	_, err := detector.DetectDrift("mock.tfstate")
 	assert.NoError(t, err)
 }
 
@@ -110,7 +118,7 @@ func TestDetectDrift_FetchAWSConfigsError(t *testing.T) {
 	logger := &mockLogger{}
 	detector := newDriftDetector(mockAWS, mockTF, logger)
 
-	err := detector.DetectDrift("mock.tfstate")
+	_, err := detector.DetectDrift("mock.tfstate")
 	assert.ErrorIs(t, err, entities.ErrFetchAWSConfigs)
 }
 
@@ -125,7 +133,7 @@ func TestDetectDrift_EmptyAWSConfigs(t *testing.T) {
 	logger := &mockLogger{}
 	detector := newDriftDetector(mockAWS, mockTF, logger)
 
-	err := detector.DetectDrift("mock.tfstate")
+	_, err := detector.DetectDrift("mock.tfstate")
 	assert.ErrorIs(t, err, entities.ErrEmptyConfigs)
 }
 
@@ -147,7 +155,7 @@ func TestDetectDrift_ParseTFStateError(t *testing.T) {
 	logger := &mockLogger{}
 	detector := newDriftDetector(mockAWS, mockTF, logger)
 
-	err := detector.DetectDrift("mock.tfstate")
+	_, err := detector.DetectDrift("mock.tfstate")
 	assert.ErrorIs(t, err, entities.ErrInvalidTerraformState)
 }
 
@@ -169,7 +177,7 @@ func TestDetectDrift_EmptyTFConfigs(t *testing.T) {
 	logger := &mockLogger{}
 	detector := newDriftDetector(mockAWS, mockTF, logger)
 
-	err := detector.DetectDrift("mock.tfstate")
+	_, err := detector.DetectDrift("mock.tfstate")
 	assert.ErrorIs(t, err, entities.ErrEmptyConfigs)
 }
 
@@ -213,6 +221,405 @@ func TestDetectDrift_WithDriftDetected(t *testing.T) {
 	logger := &mockLogger{}
 	detector := newDriftDetector(mockAWS, mockTF, logger)
 
-	err := detector.DetectDrift("mock.tfstate")
+	result, err := detector.DetectDrift("mock.tfstate")
+	assert.NoError(t, err)
+	assert.Equal(t, []entities.StateVersion{{Source: "mock.tfstate"}}, result.States)
+	assert.False(t, result.EndTime.Before(result.StartTime))
+	assert.Equal(t, entities.DriftCounts{Compared: 1, Modified: 1}, result.Counts)
+	if assert.Len(t, result.Reports, 1) {
+		report := result.Reports[0]
+		assert.Equal(t, "i-67890", report.InstanceID)
+		assert.Equal(t, entities.DriftModified, report.Kind)
+		assert.True(t, report.HasDrift)
+		assert.Equal(t, entities.Change{Expected: []string{"t2.micro"}, Actual: "t3.medium", Kind: entities.ChangeModified, Severity: entities.SeverityMedium}, report.Changes["instance_type"])
+		assert.Equal(t, []string{"sg-456"}, report.Changes["vpc_security_group_ids"].Attached)
+		assert.Contains(t, report.Changes, `ebs_block_device[""].volume_size`)
+		assert.Len(t, report.Changes, 8)
+		assert.Equal(t, entities.SeverityCritical, report.Severity)
+	}
+	assert.Equal(t, entities.SeverityCritical, result.MaxSeverity)
+	assert.Positive(t, result.RiskScore)
+}
+
+func TestDetectDrift_PairsInstancesByID(t *testing.T) {
+	mockAWS := &mockAWSClient{
+		fetchConfigs: func() ([]entities.InstanceConfig, error) {
+			return []entities.InstanceConfig{
+				{InstanceID: "i-web0", InstanceType: "t2.micro", SubnetID: "subnet-b"},
+				{InstanceID: "i-web1", InstanceType: "t2.micro", SubnetID: "subnet-b"},
+			}, nil
+		},
+	}
+
+	mockTF := &mockTFStateParser{
+		parseFunc: func(tfStateFile string) (terraform.InstanceConfigSet, error) {
+			return terraform.InstanceConfigSet{
+				Format: terraform.FormatV4,
+				Instances: map[string]terraform.InstanceResource{
+					"i-web0": {Type: "aws_instance", Name: "web", IndexKey: float64(0), Config: entities.InstanceConfig{InstanceID: "i-web0", InstanceType: "t2.micro", SubnetID: "subnet-a"}},
+					"i-web1": {Type: "aws_instance", Name: "web", IndexKey: float64(1), Config: entities.InstanceConfig{InstanceID: "i-web1", InstanceType: "t2.micro", SubnetID: "subnet-b"}},
+				},
+				InstanceTypes: []string{"t2.micro"},
+				SubnetIDs:     []string{"subnet-a", "subnet-b"},
+			}, nil
+		},
+	}
+
+	logger := &mockLogger{}
+	detector := newDriftDetector(mockAWS, mockTF, logger)
+
+	result, err := detector.DetectDrift("mock.tfstate")
+	assert.NoError(t, err)
+	assert.Equal(t, entities.DriftCounts{Compared: 2, Modified: 1}, result.Counts)
+	assert.Equal(t, []entities.DriftReport{
+		{
+			InstanceID: "i-web0",
+			Address:    "aws_instance.web[0]",
+			Kind:       entities.DriftModified,
+			HasDrift:   true,
+			Severity:   entities.SeverityHigh,
+			Changes:    map[string]entities.Change{"subnet_id": {Expected: "subnet-a", Actual: "subnet-b", Kind: entities.ChangeModified, Severity: entities.SeverityHigh}},
+		},
+		{InstanceID: "i-web1", Address: "aws_instance.web[1]"},
+	}, result.Reports)
+}
+
+func TestDetectDrift_ReportsUnmanagedAndMissing(t *testing.T) {
+	mockAWS := &mockRegionAWSClient{
+		mockAWSClient: mockAWSClient{
+			fetchConfigs: func() ([]entities.InstanceConfig, error) {
+				return []entities.InstanceConfig{
+					{InstanceID: "i-web", InstanceType: "t2.micro"},
+					{InstanceID: "i-stray", InstanceType: "t2.micro"},
+				}, nil
+			},
+		},
+		region: "eu-west-1",
+	}
+	mockTF := &mockTFStateParser{
+		parseFunc: func(tfStateFile string) (terraform.InstanceConfigSet, error) {
+			set := stateWith(
+				terraform.InstanceResource{Type: "aws_instance", Name: "web", Config: entities.InstanceConfig{InstanceID: "i-web", InstanceType: "t2.micro"}},
+				terraform.InstanceResource{Type: "aws_instance", Name: "gone", Config: entities.InstanceConfig{InstanceID: "i-gone", InstanceType: "t2.micro"}},
+			)
+			set.States = []terraform.StateVersion{{Source: tfStateFile, Serial: 7, Lineage: "3f1c-lineage", TerraformVersion: "1.7.5"}}
+			return set, nil
+		},
+	}
+	detector := NewDriftDetector(mockAWS, &mockLogger{}, WithTFParser(mockTF))
+
+	result, err := detector.DetectDrift("mock.tfstate")
+	assert.NoError(t, err)
+	assert.Equal(t, "eu-west-1", result.Region)
+	assert.Equal(t, []entities.StateVersion{
+		{Source: "mock.tfstate", Serial: 7, Lineage: "3f1c-lineage", TerraformVersion: "1.7.5"},
+	}, result.States)
+	assert.Equal(t, entities.DriftCounts{Compared: 1, Unmanaged: 1, Missing: 1}, result.Counts)
+	assert.Equal(t, []entities.DriftReport{
+		{InstanceID: "i-stray", Kind: entities.DriftUnmanaged, HasDrift: true, Severity: entities.SeverityHigh},
+		{InstanceID: "i-gone", Address: "aws_instance.gone", Kind: entities.DriftMissing, HasDrift: true, Severity: entities.SeverityHigh},
+		{InstanceID: "i-web", Address: "aws_instance.web"},
+	}, result.Reports)
+}
+
+func TestCompareInstance(t *testing.T) {
+	tfConfig := entities.InstanceConfig{
+		InstanceID:         "i-web0",
+		InstanceType:       "t2.micro",
+		SecurityGroupIDs:   []string{"sg-1", "sg-2"},
+		SubnetID:           "subnet-a",
+		IAMInstanceProfile: "web-server-role",
+		AMI:                "ami-0c55b159cbfafe1f0",
+		AvailabilityZone:   "us-east-1a",
+		KeyName:            "deploy",
+		Tags:               map[string]string{"Name": "web-0", "Environment": "prod"},
+		EBSBlockDevices: []entities.EBSBlockDevice{
+			{DeviceName: "/dev/xvda", VolumeSize: 8, VolumeType: "gp3", Iops: 3000, Throughput: 125, Root: true},
+			{DeviceName: "/dev/sdf", VolumeSize: 100, VolumeType: "gp2", Iops: 300},
+		},
+	}
+
+	t.Run("NoDrift", func(t *testing.T) {
+		awsConfig := tfConfig
+		awsConfig.SecurityGroupIDs = []string{"sg-2", "sg-1"}
+
+		changes, err := compareInstance(awsConfig, tfConfig)
+		assert.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+
+	t.Run("SwappedToAnotherManagedSubnet", func(t *testing.T) {
+		awsConfig := tfConfig
+		awsConfig.SubnetID = "subnet-b"
+		awsConfig.InstanceType = "t3.micro"
+		awsConfig.Tags = map[string]string{"Name": "web-0"}
+		awsConfig.EBSBlockDevices = []entities.EBSBlockDevice{
+			{DeviceName: "/dev/xvda", VolumeSize: 20, VolumeType: "gp3", Iops: 3000, Throughput: 125, Root: true},
+			{DeviceName: "/dev/sdf", VolumeSize: 100, VolumeType: "gp2", Iops: 300},
+		}
+
+		changes, err := compareInstance(awsConfig, tfConfig)
+		assert.NoError(t, err)
+		assert.Equal(t, []AttributeChange{
+			{Path: "instance_type", Kind: entities.ChangeModified, From: "t2.micro", To: "t3.micro"},
+			{Path: "root_block_device[0].volume_size", Kind: entities.ChangeModified, From: 8, To: 20},
+			{Path: "subnet_id", Kind: entities.ChangeModified, From: "subnet-a", To: "subnet-b"},
+			{Path: "tags.Environment", Kind: entities.ChangeRemoved, From: "prod"},
+		}, changes)
+	})
+
+	t.Run("RebuiltOutOfBand", func(t *testing.T) {
+		awsConfig := tfConfig
+		awsConfig.AMI = "ami-0f8ca7285bddd64b6"
+		awsConfig.AvailabilityZone = "us-east-1b"
+		awsConfig.KeyName = ""
+
+		changes, err := compareInstance(awsConfig, tfConfig)
+		assert.NoError(t, err)
+		assert.Equal(t, []AttributeChange{
+			{Path: "ami", Kind: entities.ChangeModified, From: "ami-0c55b159cbfafe1f0", To: "ami-0f8ca7285bddd64b6"},
+			{Path: "availability_zone", Kind: entities.ChangeModified, From: "us-east-1a", To: "us-east-1b"},
+			{Path: "key_name", Kind: entities.ChangeModified, From: "deploy", To: ""},
+		}, changes)
+	})
+
+	t.Run("BlockDevices", func(t *testing.T) {
+		awsConfig := tfConfig
+		awsConfig.EBSBlockDevices = []entities.EBSBlockDevice{
+			// The root device is paired by its flag even when renamed by a new AMI
+			{DeviceName: "/dev/sda1", VolumeSize: 8, VolumeType: "gp3", Iops: 3000, Throughput: 125, Encrypted: true, KMSKeyID: "arn:aws:kms:us-east-1:123456789012:key/abc", Root: true},
+			{DeviceName: "/dev/sdf", VolumeSize: 100, VolumeType: "gp3", Iops: 3000, Throughput: 125},
+			{DeviceName: "/dev/sdg", VolumeID: "vol-manual", VolumeSize: 50, VolumeType: "gp3", Iops: 3000, Throughput: 125},
+		}
+
+		changes, err := compareInstance(awsConfig, tfConfig)
+		assert.NoError(t, err)
+		assert.Equal(t, []AttributeChange{
+			{Path: `ebs_block_device["/dev/sdf"].iops`, Kind: entities.ChangeModified, From: 300, To: 3000},
+			{Path: `ebs_block_device["/dev/sdf"].throughput`, Kind: entities.ChangeAdded, To: 125},
+			{Path: `ebs_block_device["/dev/sdf"].volume_type`, Kind: entities.ChangeModified, From: "gp2", To: "gp3"},
+			{Path: `ebs_block_device["/dev/sdg"]`, Kind: entities.ChangeAdded, To: map[string]interface{}{
+				"device_name": "/dev/sdg", "volume_size": 50, "volume_type": "gp3", "iops": 3000, "throughput": 125,
+			}},
+			{Path: "root_block_device[0].encrypted", Kind: entities.ChangeAdded, To: true},
+			{Path: "root_block_device[0].kms_key_id", Kind: entities.ChangeAdded, To: "arn:aws:kms:us-east-1:123456789012:key/abc"},
+		}, changes)
+
+		awsConfig.EBSBlockDevices = awsConfig.EBSBlockDevices[:1]
+		changes, err = compareInstance(awsConfig, tfConfig)
+		assert.NoError(t, err)
+		assert.Contains(t, changes, AttributeChange{Path: `ebs_block_device["/dev/sdf"]`, Kind: entities.ChangeRemoved, From: map[string]interface{}{
+			"device_name": "/dev/sdf", "volume_size": 100, "volume_type": "gp2", "iops": 300,
+		}})
+		assert.Contains(t, formatDrift("i-web0", "", changes), `  - ebs_block_device["/dev/sdf"]: AWS=(not set), Terraform=device_name=/dev/sdf, iops=300, volume_size=100, volume_type=gp2`+"\n")
+	})
+
+	t.Run("EmptyInstanceID", func(t *testing.T) {
+		_, err := compareInstance(entities.InstanceConfig{}, tfConfig)
+		assert.ErrorIs(t, err, entities.ErrConfigComparison)
+	})
+
+	t.Run("VolumeWithoutSize", func(t *testing.T) {
+		awsConfig := tfConfig
+		awsConfig.EBSBlockDevices = []entities.EBSBlockDevice{{DeviceName: "/dev/sdf"}}
+		_, err := compareInstance(awsConfig, tfConfig)
+		assert.ErrorIs(t, err, entities.ErrConfigComparison)
+	})
+}
+
+func TestFormatDrift_IncludesAddress(t *testing.T) {
+	out := formatDrift("i-web0", "aws_instance.web[0]", []AttributeChange{
+		{Path: "subnet_id", Kind: entities.ChangeModified, From: "subnet-a", To: "subnet-b"},
+	})
+	assert.Equal(t, "Drift detected for instance i-web0 (aws_instance.web[0]):\n  - subnet_id: AWS=subnet-b, Terraform=subnet-a\n", out)
+}
+
+func TestRedactSensitive(t *testing.T) {
+	resource := terraform.InstanceResource{Type: "aws_instance", Name: "web", Sensitive: []string{"tags.Secret", "user_data"}}
+	changes := []AttributeChange{
+		{Path: "tags.Name", Kind: entities.ChangeModified, From: "web-a", To: "web-b"},
+		{Path: "tags.Secret", Kind: entities.ChangeModified, From: "swordfish", To: "hunter2"},
+	}
+
+	redactSensitive(changes, resource)
+
+	assert.Equal(t, []AttributeChange{
+		{Path: "tags.Name", Kind: entities.ChangeModified, From: "web-a", To: "web-b"},
+		{Path: "tags.Secret", Kind: entities.ChangeModified, Sensitive: true},
+	}, changes)
+
+	out := formatDrift("i-web0", "aws_instance.web", changes[1:])
+	assert.Equal(t, "Drift detected for instance i-web0 (aws_instance.web):\n  - tags.Secret: (sensitive value changed)\n", out)
+	assert.NotContains(t, out, "hunter2")
+}
+
+func TestDropUnknown(t *testing.T) {
+	resource := terraform.InstanceResource{Type: "aws_instance", Name: "web", Unknown: []string{"ebs_block_device", "instance_type", "root_block_device[0].volume_type", "tags.Owner"}}
+	changes := []AttributeChange{
+		{Path: `ebs_block_device["/dev/sdf"]`, Kind: entities.ChangeAdded, To: map[string]interface{}{"device_name": "/dev/sdf", "volume_size": 100}},
+		{Path: "instance_type", Kind: entities.ChangeModified, From: "", To: "t3.large"},
+		{Path: "root_block_device[0].volume_size", Kind: entities.ChangeModified, From: 8, To: 20},
+		{Path: "root_block_device[0].volume_type", Kind: entities.ChangeModified, From: "", To: "gp2"},
+		{Path: "tags.Name", Kind: entities.ChangeModified, From: "web-a", To: "web-b"},
+		{Path: "tags.Owner", Kind: entities.ChangeAdded, To: "ops"},
+	}
+
+	assert.Equal(t, []AttributeChange{
+		{Path: "root_block_device[0].volume_size", Kind: entities.ChangeModified, From: 8, To: 20},
+		{Path: "tags.Name", Kind: entities.ChangeModified, From: "web-a", To: "web-b"},
+	}, dropUnknown(changes, resource))
+}
+
+func TestDetectDrift_UncomparedResourcesClaimTheirInstances(t *testing.T) {
+	mockAWS := &mockAWSClient{
+		fetchConfigs: func() ([]entities.InstanceConfig, error) {
+			return []entities.InstanceConfig{{InstanceID: "i-db", InstanceType: "r5.large", Tags: map[string]string{"Name": "db"}}}, nil
+		},
+	}
+	// As read from .tf files, which do not cover child modules
+	mockTF := &mockTFStateParser{
+		parseFunc: func(string) (terraform.InstanceConfigSet, error) {
+			set := stateWith(terraform.InstanceResource{Module: "module.db", Type: "aws_instance", Name: "main", Config: entities.InstanceConfig{InstanceID: "i-db"}})
+			set.Format = terraform.FormatHCL
+			r := set.Instances["i-db"]
+			r.Unknown = []string{terraform.AllAttributes}
+			set.Instances["i-db"] = r
+			return set, nil
+		},
+	}
+
+	result, err := newDriftDetector(mockAWS, mockTF, &mockLogger{}).DetectDrift("infrastructure/app")
+	assert.NoError(t, err)
+	assert.Equal(t, entities.DriftCounts{Compared: 1}, result.Counts)
+	assert.Equal(t, []entities.DriftReport{{InstanceID: "i-db", Address: "module.db.aws_instance.main"}}, result.Reports)
+}
+
+func TestDetectDrift_HCLUnknownVolumeAttributes(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`
+resource "aws_instance" "web" {
+  ami                    = "ami-1"
+  instance_type          = "t3.micro"
+  availability_zone      = "us-east-1a"
+  subnet_id              = "subnet-1"
+  vpc_security_group_ids = ["sg-1"]
+
+  ebs_block_device {
+    device_name = "/dev/sdb"
+    volume_size = 100
+    volume_type = "gp3"
+  }
+}
+`), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "terraform.tfstate"), []byte(`{"version": 4, "serial": 1, "lineage": "l", "resources": [
+		{"mode": "managed", "type": "aws_instance", "name": "web", "instances": [{"attributes": {"id": "i-web",
+			"root_block_device": [{"device_name": "/dev/xvda", "volume_size": 8}]}}]}
+	]}`), 0o644))
+	mockAWS := &mockAWSClient{
+		fetchConfigs: func() ([]entities.InstanceConfig, error) {
+			return []entities.InstanceConfig{{
+				InstanceID:       "i-web",
+				InstanceType:     "t3.micro",
+				AMI:              "ami-1",
+				AvailabilityZone: "us-east-1a",
+				SubnetID:         "subnet-1",
+				SecurityGroupIDs: []string{"sg-1"},
+				EBSBlockDevices: []entities.EBSBlockDevice{
+					{DeviceName: "/dev/xvda", VolumeSize: 30, VolumeType: "gp3", Iops: 3000, Root: true},
+					// Resized, with IOPS the configuration leaves to AWS
+					{DeviceName: "/dev/sdb", VolumeSize: 500, VolumeType: "gp3", Iops: 3000, Throughput: 125},
+					// Attached by hand
+					{DeviceName: "/dev/sdz", VolumeSize: 20, VolumeType: "gp2"},
+				},
+			}}, nil
+		},
+	}
+	parser := terraform.NewHCLConfigParser(&mockLogger{}, terraform.NewTFStateParser(&mockLogger{}), "")
+	detector := NewDriftDetector(mockAWS, &mockLogger{}, WithTFParser(parser))
+
+	result, err := detector.DetectDrift(dir)
 	assert.NoError(t, err)
+	if assert.Len(t, result.Reports, 1) {
+		changes := result.Reports[0].Changes
+		assert.Len(t, changes, 2)
+		assert.Equal(t, 500, changes[`ebs_block_device["/dev/sdb"].volume_size`].Actual)
+		assert.Equal(t, entities.ChangeAdded, changes[`ebs_block_device["/dev/sdz"]`].Kind)
+	}
+}
+
+func TestClassifyInstances(t *testing.T) {
+	tfConfigs := terraform.InstanceConfigSet{
+		Format: terraform.FormatV4,
+		Instances: map[string]terraform.InstanceResource{
+			"i-web0": {Type: "aws_instance", Name: "web", IndexKey: float64(0), Config: entities.InstanceConfig{InstanceID: "i-web0"}},
+			"i-web1": {Type: "aws_instance", Name: "web", IndexKey: float64(1), Config: entities.InstanceConfig{InstanceID: "i-web1"}},
+			"i-web2": {Type: "aws_instance", Name: "web", IndexKey: float64(2), Config: entities.InstanceConfig{InstanceID: "i-web2"}},
+		},
+	}
+	awsConfigs := []entities.InstanceConfig{
+		{InstanceID: "i-web0", State: "running"},
+		{InstanceID: "i-web1", State: entities.InstanceStateTerminated},
+		{InstanceID: "i-manual", State: "running"},
+		{InstanceID: "i-old", State: entities.InstanceStateTerminated},
+	}
+
+	paired, unmanaged, missing := classifyInstances(awsConfigs, tfConfigs)
+
+	assert.Len(t, paired, 1)
+	assert.Equal(t, "i-web0", paired[0].InstanceID)
+	assert.Len(t, unmanaged, 1)
+	assert.Equal(t, "i-manual", unmanaged[0].InstanceID)
+	assert.Len(t, missing, 2)
+	assert.Equal(t, "i-web1", missing[0].resource.Config.InstanceID)
+	assert.Equal(t, entities.InstanceStateTerminated, missing[0].state)
+	assert.Equal(t, "i-web2", missing[1].resource.Config.InstanceID)
+	assert.Empty(t, missing[1].state)
+
+	assert.Equal(t, "Drift detected for instance i-web1 (aws_instance.web[1]): missing, declared in Terraform state but instance is terminated in AWS\n", formatMissing(missing[0]))
+	assert.Equal(t, "Drift detected for instance i-web2 (aws_instance.web[2]): missing, declared in Terraform state but not returned by AWS\n", formatMissing(missing[1]))
+}
+
+func TestClassifyInstances_LegacyStateComparesEverything(t *testing.T) {
+	awsConfigs := []entities.InstanceConfig{{InstanceID: "i-1"}, {InstanceID: "i-2"}}
+
+	paired, unmanaged, missing := classifyInstances(awsConfigs, terraform.InstanceConfigSet{InstanceTypes: []string{"t2.micro"}})
+
+	assert.Equal(t, awsConfigs, paired)
+	assert.Empty(t, unmanaged)
+	assert.Empty(t, missing)
+}
+
+func TestFormatUnmanaged(t *testing.T) {
+	out := formatUnmanaged(entities.InstanceConfig{
+		InstanceID:   "i-manual",
+		InstanceType: "t3.large",
+		SubnetID:     "subnet-a",
+		Tags:         map[string]string{"Name": "hand-made"},
+	})
+	assert.Equal(t, "Drift detected for instance i-manual: unmanaged, no Terraform resource claims this ID (instance_type=t3.large, subnet_id=subnet-a, tags.Name=hand-made)\n", out)
+}
+
+func TestFilterByAddressPrefix(t *testing.T) {
+	tfConfigs := terraform.InstanceConfigSet{
+		Format: terraform.FormatV4,
+		Instances: map[string]terraform.InstanceResource{
+			"i-app":     {Module: "module.app", Type: "aws_instance", Name: "web", Config: entities.InstanceConfig{InstanceID: "i-app"}},
+			"i-app-old": {Module: "module.app", Type: "aws_instance", Name: "old", Config: entities.InstanceConfig{InstanceID: "i-app-old"}},
+			"i-db":      {Module: "module.db", Type: "aws_instance", Name: "primary", Config: entities.InstanceConfig{InstanceID: "i-db"}},
+			"i-db-old":  {Module: "module.db", Type: "aws_instance", Name: "old", Config: entities.InstanceConfig{InstanceID: "i-db-old"}},
+		},
+	}
+	awsConfigs := []entities.InstanceConfig{
+		{InstanceID: "i-app", State: "running"},
+		{InstanceID: "i-db", State: "running"},
+		{InstanceID: "i-manual", State: "running"},
+	}
+
+	paired, _, missing := classifyInstances(awsConfigs, tfConfigs)
+	paired, missing = filterByAddressPrefix(paired, missing, tfConfigs, "module.app")
+
+	assert.Len(t, paired, 1)
+	assert.Equal(t, "i-app", paired[0].InstanceID)
+	assert.Len(t, missing, 1)
+	assert.Equal(t, "i-app-old", missing[0].resource.Config.InstanceID)
 }
@@ -0,0 +1,150 @@
+package usecases
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+	"github.com/cstudio7/drift-detector/internal/interfaces/logger"
+	"github.com/cstudio7/drift-detector/internal/interfaces/terraform"
+)
+
+// StateDiffer compares two Terraform states resource by resource, without reading anything from AWS.
+type StateDiffer struct {
+	tfParser terraform.TFStateParser
+	logger   logger.Logger
+}
+
+// NewStateDiffer returns a StateDiffer that reads both states with tfParser.
+func NewStateDiffer(tfParser terraform.TFStateParser, logger logger.Logger) *StateDiffer {
+	return &StateDiffer{tfParser: tfParser, logger: logger}
+}
+
+// ResourceChange is a resource declared in both states whose attributes differ.
+type ResourceChange struct {
+	From terraform.InstanceResource
+	To   terraform.InstanceResource
+	// Changes are the attributes that differ, from the old state to the new one, in path order.
+	Changes []AttributeChange
+}
+
+// StateDiff is the difference between two states. Resources are matched by address, so a resource
+// replaced with a new instance is changed, not removed and added.
+type StateDiff struct {
+	Added   []terraform.InstanceResource
+	Removed []terraform.InstanceResource
+	Changed []ResourceChange
+}
+
+// DiffStates parses both states and reports the resources only in toFile as added, those only in
+// fromFile as removed, and those in both whose attributes differ as changed.
+func (s *StateDiffer) DiffStates(fromFile, toFile string) (StateDiff, error) {
+	from, err := s.parseState(fromFile)
+	if err != nil {
+		return StateDiff{}, err
+	}
+	to, err := s.parseState(toFile)
+	if err != nil {
+		return StateDiff{}, err
+	}
+
+	diff := diffResources(from, to)
+	fromFile, toFile = terraform.RedactLocation(fromFile), terraform.RedactLocation(toFile)
+	for _, resource := range diff.Added {
+		s.logger.Info(formatAdded(resource, toFile))
+	}
+	for _, resource := range diff.Removed {
+		s.logger.Info(formatRemoved(resource, toFile))
+	}
+	for _, change := range diff.Changed {
+		s.logger.Info(formatChange(change))
+	}
+	s.logger.Info("State diff summary", "from", fromFile, "to", toFile, "added", len(diff.Added), "removed", len(diff.Removed), "changed", len(diff.Changed))
+	return diff, nil
+}
+
+// parseState parses one state into its resources keyed by address. Legacy states only hold
+// aggregated attribute lists, which cannot be diffed resource by resource.
+func (s *StateDiffer) parseState(tfStateFile string) (map[string]terraform.InstanceResource, error) {
+	configs, err := s.tfParser.ParseTFState(tfStateFile)
+	tfStateFile = terraform.RedactLocation(tfStateFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", entities.ErrInvalidTerraformState, tfStateFile, err)
+	}
+	if len(configs.Instances) == 0 && !configs.IsEmpty() {
+		return nil, fmt.Errorf("%w: %s holds no per-resource data to diff", entities.ErrInvalidTerraformState, tfStateFile)
+	}
+	for _, state := range configs.States {
+		s.logger.Info("State version", "source", tfStateFile, "serial", state.Serial, "lineage", state.Lineage)
+	}
+
+	resources := make(map[string]terraform.InstanceResource, len(configs.Instances))
+	for _, resource := range configs.Instances {
+		resources[resource.Address()] = resource
+	}
+	return resources, nil
+}
+
+// diffResources compares two sets of resources keyed by address, in address order.
+func diffResources(from, to map[string]terraform.InstanceResource) StateDiff {
+	var diff StateDiff
+	for _, address := range sortedAddresses(to) {
+		if _, ok := from[address]; !ok {
+			diff.Added = append(diff.Added, to[address])
+		}
+	}
+	for _, address := range sortedAddresses(from) {
+		fromResource := from[address]
+		toResource, ok := to[address]
+		if !ok {
+			diff.Removed = append(diff.Removed, fromResource)
+			continue
+		}
+
+		// Neither state is live, so the checks compareInstance makes on live values do not apply
+		changes := diffInstances(fromResource.Config, toResource.Config)
+		for _, resource := range []terraform.InstanceResource{fromResource, toResource} {
+			changes = dropUnknown(changes, resource)
+			redactSensitive(changes, resource)
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		diff.Changed = append(diff.Changed, ResourceChange{From: fromResource, To: toResource, Changes: changes})
+	}
+	return diff
+}
+
+func sortedAddresses(resources map[string]terraform.InstanceResource) []string {
+	addresses := make([]string, 0, len(resources))
+	for address := range resources {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+func formatAdded(r terraform.InstanceResource, toFile string) string {
+	return fmt.Sprintf("Resource added in %s: %s (instance %s)\n", toFile, r.Address(), r.Config.InstanceID)
+}
+
+func formatRemoved(r terraform.InstanceResource, toFile string) string {
+	return fmt.Sprintf("Resource removed in %s: %s (instance %s)\n", toFile, r.Address(), r.Config.InstanceID)
+}
+
+func formatChange(change ResourceChange) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Resource changed: %s:\n", change.To.Address()))
+	for _, c := range change.Changes {
+		switch {
+		case c.Sensitive:
+			b.WriteString(fmt.Sprintf("  - %s: %s\n", c.Path, sensitiveValueChanged))
+		case len(c.Added) > 0 || len(c.Removed) > 0:
+			b.WriteString(fmt.Sprintf("  - %s: %s -> %s (%s)\n", c.Path, formatValue(c.From), formatValue(c.To), formatSetChange(c)))
+		default:
+			b.WriteString(fmt.Sprintf("  - %s: %s -> %s\n", c.Path, formatValue(c.From), formatValue(c.To)))
+		}
+	}
+	return b.String()
+}
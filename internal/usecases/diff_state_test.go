@@ -0,0 +1,109 @@
+package usecases
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+	"github.com/cstudio7/drift-detector/internal/interfaces/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffStates(t *testing.T) {
+	states := map[string]terraform.InstanceConfigSet{
+		"old.tfstate": stateWith(
+			terraform.InstanceResource{Type: "aws_instance", Name: "web", Config: entities.InstanceConfig{InstanceID: "i-web", InstanceType: "t3.micro", SubnetID: "subnet-1"}},
+			terraform.InstanceResource{Type: "aws_instance", Name: "worker", Config: entities.InstanceConfig{InstanceID: "i-worker", InstanceType: "t3.small"}},
+			terraform.InstanceResource{Type: "aws_instance", Name: "db", Config: entities.InstanceConfig{InstanceID: "i-db", InstanceType: "r5.large"}},
+			terraform.InstanceResource{Type: "aws_instance", Name: "secret", Sensitive: []string{"tags.Name"}, Config: entities.InstanceConfig{InstanceID: "i-secret", InstanceType: "t3.nano", Tags: map[string]string{"Name": "a"}}},
+		),
+		"new.tfstate": stateWith(
+			terraform.InstanceResource{Type: "aws_instance", Name: "web", Config: entities.InstanceConfig{InstanceID: "i-web", InstanceType: "t3.large", SubnetID: "subnet-1"}},
+			terraform.InstanceResource{Type: "aws_instance", Name: "worker", Config: entities.InstanceConfig{InstanceID: "i-worker2", InstanceType: "t3.small"}},
+			terraform.InstanceResource{Type: "aws_instance", Name: "cache", Module: "module.app", Config: entities.InstanceConfig{InstanceID: "i-cache", InstanceType: "t3.medium"}},
+			terraform.InstanceResource{Type: "aws_instance", Name: "secret", Sensitive: []string{"tags.Name"}, Config: entities.InstanceConfig{InstanceID: "i-secret", InstanceType: "t3.nano", Tags: map[string]string{"Name": "b"}}},
+		),
+	}
+	mockTF := &mockTFStateParser{
+		parseFunc: func(tfStateFile string) (terraform.InstanceConfigSet, error) {
+			return states[tfStateFile], nil
+		},
+	}
+	differ := NewStateDiffer(mockTF, &mockLogger{})
+
+	diff, err := differ.DiffStates("old.tfstate", "new.tfstate")
+	assert.NoError(t, err)
+
+	if assert.Len(t, diff.Added, 1) {
+		assert.Equal(t, "module.app.aws_instance.cache", diff.Added[0].Address())
+	}
+	if assert.Len(t, diff.Removed, 1) {
+		assert.Equal(t, "aws_instance.db", diff.Removed[0].Address())
+	}
+	if assert.Len(t, diff.Changed, 3) {
+		assert.Equal(t, "aws_instance.secret", diff.Changed[0].To.Address())
+		assert.Equal(t, []AttributeChange{{Path: "tags.Name", Kind: entities.ChangeModified, Sensitive: true}}, diff.Changed[0].Changes)
+		assert.Equal(t, "aws_instance.web", diff.Changed[1].To.Address())
+		assert.Equal(t, []AttributeChange{{Path: "instance_type", Kind: entities.ChangeModified, From: "t3.micro", To: "t3.large"}}, diff.Changed[1].Changes)
+		assert.Equal(t, "aws_instance.worker", diff.Changed[2].To.Address())
+		assert.Equal(t, []AttributeChange{{Path: "id", Kind: entities.ChangeModified, From: "i-worker", To: "i-worker2"}}, diff.Changed[2].Changes)
+	}
+
+	assert.Equal(t, "Resource changed: aws_instance.web:\n  - instance_type: t3.micro -> t3.large\n", formatChange(diff.Changed[1]))
+}
+
+func TestDiffStates_Errors(t *testing.T) {
+	legacy := terraform.InstanceConfigSet{Format: terraform.FormatLegacy, InstanceTypes: []string{"t2.micro"}}
+	mockTF := &mockTFStateParser{
+		parseFunc: func(tfStateFile string) (terraform.InstanceConfigSet, error) {
+			if tfStateFile == "broken.tfstate" {
+				return terraform.InstanceConfigSet{}, errors.New("unexpected end of JSON input")
+			}
+			return legacy, nil
+		},
+	}
+	differ := NewStateDiffer(mockTF, &mockLogger{})
+
+	_, err := differ.DiffStates("broken.tfstate", "legacy.tfstate")
+	assert.ErrorIs(t, err, entities.ErrInvalidTerraformState)
+
+	_, err = differ.DiffStates("legacy.tfstate", "legacy.tfstate")
+	assert.ErrorIs(t, err, entities.ErrInvalidTerraformState)
+	assert.Contains(t, err.Error(), "no per-resource data")
+}
+
+func TestDiffStates_SecurityGroupsAndSizelessVolumes(t *testing.T) {
+	states := map[string]terraform.InstanceConfigSet{
+		"old.tfstate": stateWith(
+			terraform.InstanceResource{Type: "aws_instance", Name: "web", Config: entities.InstanceConfig{
+				InstanceID:       "i-web",
+				SecurityGroupIDs: []string{"sg-admin", "sg-web"},
+				EBSBlockDevices:  []entities.EBSBlockDevice{{DeviceName: "/dev/sdf", VolumeSize: 100}},
+			}},
+		),
+		"new.tfstate": stateWith(
+			terraform.InstanceResource{Type: "aws_instance", Name: "web", Config: entities.InstanceConfig{
+				InstanceID:       "i-web",
+				SecurityGroupIDs: []string{"sg-web"},
+				EBSBlockDevices:  []entities.EBSBlockDevice{{DeviceName: "/dev/sdf"}},
+			}},
+		),
+	}
+	mockTF := &mockTFStateParser{
+		parseFunc: func(tfStateFile string) (terraform.InstanceConfigSet, error) {
+			return states[tfStateFile], nil
+		},
+	}
+	differ := NewStateDiffer(mockTF, &mockLogger{})
+
+	diff, err := differ.DiffStates("old.tfstate", "new.tfstate")
+	assert.NoError(t, err)
+
+	if assert.Len(t, diff.Changed, 1) {
+		assert.Equal(t, []AttributeChange{
+			{Path: `ebs_block_device["/dev/sdf"].volume_size`, Kind: entities.ChangeModified, From: 100, To: 0},
+			{Path: "vpc_security_group_ids", Kind: entities.ChangeModified, From: []string{"sg-admin", "sg-web"}, To: []string{"sg-web"}, Removed: []string{"sg-admin"}},
+		}, diff.Changed[0].Changes)
+		assert.Contains(t, formatChange(diff.Changed[0]), "  - vpc_security_group_ids: sg-admin, sg-web -> sg-web (detached sg-admin)\n")
+	}
+}
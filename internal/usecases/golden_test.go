@@ -0,0 +1,214 @@
+package usecases
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+	"github.com/cstudio7/drift-detector/internal/interfaces/terraform"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// recordingLogger keeps what is logged, as StdLogger prints it but without timestamps.
+type recordingLogger struct {
+	mu  sync.Mutex
+	out strings.Builder
+}
+
+func (l *recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.record("INFO", msg, keysAndValues)
+}
+
+func (l *recordingLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.record("WARN", msg, keysAndValues)
+}
+
+func (l *recordingLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.record("ERROR", msg, keysAndValues)
+}
+
+func (l *recordingLogger) record(level, msg string, keysAndValues []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(&l.out, "[%s] %s %v\n", level, msg, keysAndValues)
+}
+
+// assertGolden compares got with testdata/name, or rewrites the file when the tests run with -update.
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		assert.NoError(t, os.WriteFile(path, []byte(got), 0o644))
+		return
+	}
+	want, err := os.ReadFile(path)
+	if assert.NoError(t, err, "run go test ./internal/usecases -update to create it") {
+		assert.Equal(t, string(want), got)
+	}
+}
+
+func goldenFleet() ([]entities.InstanceConfig, terraform.InstanceConfigSet) {
+	live := []entities.InstanceConfig{
+		{InstanceID: "i-stray2", InstanceType: "t2.micro", SubnetID: "subnet-b", Tags: map[string]string{"Name": "scratch"}},
+		{
+			InstanceID:       "i-web1",
+			InstanceType:     "t3.large",
+			SubnetID:         "subnet-a",
+			SecurityGroupIDs: []string{"sg-web", "sg-debug", "sg-ssh"},
+			Tags:             map[string]string{"Name": "web-1", "Owner": "ops", "Environment": "staging"},
+			EBSBlockDevices: []entities.EBSBlockDevice{
+				{DeviceName: "/dev/xvda", VolumeSize: 16, VolumeType: "gp3", Root: true},
+				{DeviceName: "/dev/sdg", VolumeSize: 50, VolumeType: "gp3"},
+			},
+		},
+		{InstanceID: "i-stray1", InstanceType: "t3.nano", SubnetID: "subnet-a"},
+		{InstanceID: "i-batch", InstanceType: "c5.xlarge", SubnetID: "subnet-c", KeyName: "ops"},
+		{
+			InstanceID:       "i-web0",
+			InstanceType:     "t3.micro",
+			SubnetID:         "subnet-b",
+			SecurityGroupIDs: []string{"sg-web"},
+			Tags:             map[string]string{"Name": "web-0", "Team": "platform", "Environment": "prod"},
+		},
+		{InstanceID: "i-api", InstanceType: "t3.small", SubnetID: "subnet-a", SecurityGroupIDs: []string{"sg-api"}},
+	}
+	state := stateWith(
+		terraform.InstanceResource{Type: "aws_instance", Name: "web", IndexKey: float64(1), Config: entities.InstanceConfig{
+			InstanceID:       "i-web1",
+			InstanceType:     "t3.micro",
+			SubnetID:         "subnet-a",
+			SecurityGroupIDs: []string{"sg-web", "sg-admin"},
+			Tags:             map[string]string{"Name": "web-1", "Environment": "prod", "CostCenter": "42"},
+			EBSBlockDevices: []entities.EBSBlockDevice{
+				{DeviceName: "/dev/xvda", VolumeSize: 8, VolumeType: "gp3", Root: true},
+				{DeviceName: "/dev/sdf", VolumeSize: 100, VolumeType: "gp2"},
+			},
+		}},
+		terraform.InstanceResource{Type: "aws_instance", Name: "web", IndexKey: float64(0), Config: entities.InstanceConfig{
+			InstanceID:       "i-web0",
+			InstanceType:     "t3.micro",
+			SubnetID:         "subnet-a",
+			SecurityGroupIDs: []string{"sg-web"},
+			Tags:             map[string]string{"Name": "web-0", "Team": "platform", "Environment": "prod"},
+		}},
+		terraform.InstanceResource{Module: "module.batch", Type: "aws_instance", Name: "worker", Config: entities.InstanceConfig{
+			InstanceID: "i-batch", InstanceType: "c5.large", SubnetID: "subnet-c", KeyName: "ops",
+		}},
+		terraform.InstanceResource{Type: "aws_instance", Name: "api", Config: entities.InstanceConfig{
+			InstanceID: "i-api", InstanceType: "t3.small", SubnetID: "subnet-a", SecurityGroupIDs: []string{"sg-api"},
+		}},
+		terraform.InstanceResource{Type: "aws_instance", Name: "cache", Config: entities.InstanceConfig{InstanceID: "i-cache", InstanceType: "r5.large"}},
+		terraform.InstanceResource{Module: "module.app", Type: "aws_instance", Name: "db", Config: entities.InstanceConfig{InstanceID: "i-db", InstanceType: "r5.xlarge"}},
+	)
+	return live, state
+}
+
+func TestDetectDrift_Golden(t *testing.T) {
+	live, state := goldenFleet()
+	suppressions := &Suppressions{Rules: []SuppressionRule{
+		{Address: "module.batch.*", Attributes: []string{"instance_type"}, Justification: "Resized for the quarter end"},
+	}}
+	assert.NoError(t, suppressions.Rules[0].compile())
+
+	// Comparisons run concurrently, so several runs must render the same output
+	var logs, reports []string
+	for i := 0; i < 5; i++ {
+		log := &recordingLogger{}
+		detector := NewDriftDetector(
+			&mockAWSClient{fetchConfigs: func() ([]entities.InstanceConfig, error) { return live, nil }},
+			log,
+			WithTFParser(&mockTFStateParser{parseFunc: func(string) (terraform.InstanceConfigSet, error) { return state, nil }}),
+			WithSuppressions(suppressions),
+		)
+		result, err := detector.DetectDrift("prod.tfstate")
+		assert.NoError(t, err)
+		rendered, err := json.MarshalIndent(result.Reports, "", "  ")
+		assert.NoError(t, err)
+		logs = append(logs, log.out.String())
+		reports = append(reports, string(rendered)+"\n")
+	}
+	for i := 1; i < len(logs); i++ {
+		assert.Equal(t, logs[0], logs[i])
+		assert.Equal(t, reports[0], reports[i])
+	}
+
+	assertGolden(t, "detect.golden", logs[0])
+	assertGolden(t, "detect_reports.golden", reports[0])
+}
+
+func TestDetectDrift_MultipleStatesGolden(t *testing.T) {
+	live, _ := goldenFleet()
+	// The network state claims an instance of prod, and a stale copy of prod claims two more
+	states := func() map[string]terraform.InstanceConfigSet {
+		_, prod := goldenFleet()
+		prod.States = []terraform.StateVersion{{Source: "prod.tfstate", Serial: 42, Lineage: "prod-lineage", TerraformVersion: "1.7.5"}}
+		network := stateWith(
+			terraform.InstanceResource{Type: "aws_instance", Name: "nat", Config: entities.InstanceConfig{InstanceID: "i-stray1", InstanceType: "t3.nano", SubnetID: "subnet-a"}},
+			terraform.InstanceResource{Type: "aws_instance", Name: "jump", Config: entities.InstanceConfig{InstanceID: "i-batch", InstanceType: "c5.xlarge", SubnetID: "subnet-c", KeyName: "ops"}},
+		)
+		network.States = []terraform.StateVersion{{Source: "network.tfstate", Serial: 3, Lineage: "network-lineage", TerraformVersion: "1.7.5"}}
+		backup := stateWith(prod.Instances["i-web1"], prod.Instances["i-api"])
+		backup.States = []terraform.StateVersion{{Source: "prod.tfstate.backup", Serial: 40, Lineage: "prod-lineage", TerraformVersion: "1.6.2"}}
+		return map[string]terraform.InstanceConfigSet{"prod.tfstate": prod, "network.tfstate": network, "prod.tfstate.backup": backup}
+	}
+
+	var logs, results []string
+	for i := 0; i < 5; i++ {
+		parsed := states()
+		log := &recordingLogger{}
+		detector := NewDriftDetector(
+			&mockAWSClient{fetchConfigs: func() ([]entities.InstanceConfig, error) { return live, nil }},
+			log,
+			WithTFParser(&mockTFStateParser{parseFunc: func(file string) (terraform.InstanceConfigSet, error) { return parsed[file], nil }}),
+		)
+		result, err := detector.DetectDrift("prod.tfstate", "network.tfstate", "prod.tfstate.backup")
+		assert.NoError(t, err)
+		rendered, err := json.MarshalIndent(struct {
+			States []entities.StateVersion `json:"states"`
+			Counts entities.DriftCounts    `json:"counts"`
+		}{result.States, result.Counts}, "", "  ")
+		assert.NoError(t, err)
+		logs = append(logs, log.out.String())
+		results = append(results, string(rendered)+"\n")
+	}
+	for i := 1; i < len(logs); i++ {
+		assert.Equal(t, logs[0], logs[i])
+		assert.Equal(t, results[0], results[i])
+	}
+
+	assertGolden(t, "detect_multi.golden", logs[0]+results[0])
+}
+
+func TestDiffStates_Golden(t *testing.T) {
+	_, state := goldenFleet()
+	changed := stateWith(
+		terraform.InstanceResource{Type: "aws_instance", Name: "web", IndexKey: float64(1), Config: entities.InstanceConfig{
+			InstanceID:       "i-web1",
+			InstanceType:     "t3.large",
+			SubnetID:         "subnet-b",
+			SecurityGroupIDs: []string{"sg-web"},
+			Tags:             map[string]string{"Name": "web-1", "Environment": "staging", "Owner": "ops"},
+		}},
+		terraform.InstanceResource{Type: "aws_instance", Name: "web", IndexKey: float64(0), Config: state.Instances["i-web0"].Config},
+		terraform.InstanceResource{Type: "aws_instance", Name: "api", Config: entities.InstanceConfig{InstanceID: "i-api", InstanceType: "t3.medium", SubnetID: "subnet-a", SecurityGroupIDs: []string{"sg-api"}}},
+		terraform.InstanceResource{Type: "aws_instance", Name: "cache", Config: entities.InstanceConfig{InstanceID: "i-cache", InstanceType: "r5.large"}},
+		terraform.InstanceResource{Module: "module.app", Type: "aws_instance", Name: "queue", Config: entities.InstanceConfig{InstanceID: "i-queue", InstanceType: "m5.large"}},
+	)
+	states := map[string]terraform.InstanceConfigSet{"old.tfstate": state, "new.tfstate": changed}
+
+	log := &recordingLogger{}
+	differ := NewStateDiffer(&mockTFStateParser{parseFunc: func(file string) (terraform.InstanceConfigSet, error) { return states[file], nil }}, log)
+	_, err := differ.DiffStates("old.tfstate", "new.tfstate")
+	assert.NoError(t, err)
+
+	assertGolden(t, "diff_state.golden", log.out.String())
+}
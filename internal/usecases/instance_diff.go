@@ -0,0 +1,127 @@
+package usecases
+
+import (
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+)
+
+// instanceDiffOptions describes the unordered attributes of an aws_instance: security groups are
+// sets, block devices are matched by device name and network interfaces by ID, and tags are
+// compared key by key.
+var instanceDiffOptions = DiffOptions{
+	Sets:    []string{"vpc_security_group_ids", "network_interface.security_groups"},
+	SetKeys: map[string]string{"ebs_block_device": "device_name", "network_interface": "network_interface_id"},
+	Maps:    []string{"tags"},
+}
+
+// diffInstances compares two configs of an instance from the first to the second: Terraform and
+// AWS when detecting drift, or the old and the new state when diffing states. Changes are
+// reported under the aws_instance's attribute paths, e.g. instance_type, tags.Name,
+// root_block_device[0].volume_size or ebs_block_device["/dev/sdf"].iops.
+func diffInstances(from, to entities.InstanceConfig) []AttributeChange {
+	fromRoot, fromEBS := splitRootDevice(from.EBSBlockDevices)
+	toRoot, toEBS := splitRootDevice(to.EBSBlockDevices)
+	// When only one side marks a root device, as older states do not, every device is matched by name
+	if fromRoot == nil && toRoot != nil {
+		toEBS = append(toEBS, *toRoot)
+		toRoot = nil
+	}
+	if toRoot == nil && fromRoot != nil {
+		fromEBS = append(fromEBS, *fromRoot)
+		fromRoot = nil
+	}
+	fromENIs, toENIs := sharedNetworkInterfaces(from.NetworkInterfaces, to.NetworkInterfaces)
+
+	return DiffAttributes(
+		instanceAttributes(from, fromRoot, fromEBS, fromENIs),
+		instanceAttributes(to, toRoot, toEBS, toENIs),
+		instanceDiffOptions,
+	)
+}
+
+// instanceAttributes builds the attribute tree of an instance as the aws_instance resource lays
+// it out, with the given root device, additional EBS volumes and secondary network interfaces.
+func instanceAttributes(c entities.InstanceConfig, root *entities.EBSBlockDevice, ebs []entities.EBSBlockDevice, enis []entities.NetworkInterface) map[string]interface{} {
+	attributes := map[string]interface{}{
+		"id":                     c.InstanceID,
+		"instance_type":          c.InstanceType,
+		"ami":                    c.AMI,
+		"availability_zone":      c.AvailabilityZone,
+		"key_name":               c.KeyName,
+		"subnet_id":              c.SubnetID,
+		"iam_instance_profile":   c.IAMInstanceProfile,
+		"vpc_security_group_ids": c.SecurityGroupIDs,
+		"tags":                   c.Tags,
+	}
+	if root != nil {
+		attributes["root_block_device"] = []interface{}{blockDeviceAttributes(*root)}
+	}
+	devices := make([]interface{}, len(ebs))
+	for i, device := range ebs {
+		block := blockDeviceAttributes(device)
+		block["device_name"] = device.DeviceName
+		devices[i] = block
+	}
+	attributes["ebs_block_device"] = devices
+	interfaces := make([]interface{}, len(enis))
+	for i, eni := range enis {
+		interfaces[i] = map[string]interface{}{
+			"network_interface_id": eni.ID,
+			"security_groups":      eni.SecurityGroupIDs,
+		}
+	}
+	attributes["network_interface"] = interfaces
+	return attributes
+}
+
+// blockDeviceAttributes returns the compared attributes of a block device. Optional attributes
+// left at their zero value, such as the IOPS of a gp2 volume, are left out as unset.
+func blockDeviceAttributes(device entities.EBSBlockDevice) map[string]interface{} {
+	block := map[string]interface{}{
+		"volume_size": device.VolumeSize,
+		"volume_type": device.VolumeType,
+	}
+	if device.Iops != 0 {
+		block["iops"] = device.Iops
+	}
+	if device.Throughput != 0 {
+		block["throughput"] = device.Throughput
+	}
+	if device.Encrypted {
+		block["encrypted"] = true
+	}
+	if device.KMSKeyID != "" {
+		block["kms_key_id"] = device.KMSKeyID
+	}
+	return block
+}
+
+// splitRootDevice returns the device marked as root, if any, and the others.
+func splitRootDevice(devices []entities.EBSBlockDevice) (*entities.EBSBlockDevice, []entities.EBSBlockDevice) {
+	var root *entities.EBSBlockDevice
+	var additional []entities.EBSBlockDevice
+	for i := range devices {
+		if devices[i].Root && root == nil {
+			root = &devices[i]
+			continue
+		}
+		additional = append(additional, devices[i])
+	}
+	return root, additional
+}
+
+// sharedNetworkInterfaces returns the secondary network interfaces both sides know. Interfaces
+// known to one side only are not compared: only v4 states record them, through their
+// aws_network_interface resources.
+func sharedNetworkInterfaces(a, b []entities.NetworkInterface) ([]entities.NetworkInterface, []entities.NetworkInterface) {
+	var sharedA, sharedB []entities.NetworkInterface
+	for _, x := range a {
+		for _, y := range b {
+			if x.ID == y.ID {
+				sharedA = append(sharedA, x)
+				sharedB = append(sharedB, y)
+				break
+			}
+		}
+	}
+	return sharedA, sharedB
+}
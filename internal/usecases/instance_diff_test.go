@@ -0,0 +1,94 @@
+package usecases
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+)
+
+func TestDiffInstances_SecurityGroups(t *testing.T) {
+	tfConfig := entities.InstanceConfig{InstanceID: "i-web0", SecurityGroupIDs: []string{"sg-1", "sg-2"}}
+	awsConfig := entities.InstanceConfig{InstanceID: "i-web0", SecurityGroupIDs: []string{"sg-2", "sg-1"}}
+	// Order does not matter
+	assert.Empty(t, diffInstances(tfConfig, awsConfig))
+
+	awsConfig.SecurityGroupIDs = []string{"sg-4", "sg-1", "sg-3"}
+	changes := diffInstances(tfConfig, awsConfig)
+	assert.Equal(t, []AttributeChange{{
+		Path:    "vpc_security_group_ids",
+		Kind:    entities.ChangeModified,
+		From:    []string{"sg-1", "sg-2"},
+		To:      []string{"sg-1", "sg-3", "sg-4"},
+		Added:   []string{"sg-3", "sg-4"},
+		Removed: []string{"sg-2"},
+	}}, changes)
+
+	reported := toChanges(changes)
+	assert.Equal(t, []string{"sg-3", "sg-4"}, reported["vpc_security_group_ids"].Attached)
+	assert.Equal(t, []string{"sg-2"}, reported["vpc_security_group_ids"].Detached)
+
+	out := formatDrift("i-web0", "aws_instance.web", changes)
+	assert.Contains(t, out, "  - vpc_security_group_ids: attached sg-3, sg-4, detached sg-2\n")
+}
+
+func TestDiffInstances_NetworkInterfaces(t *testing.T) {
+	tfConfig := entities.InstanceConfig{
+		InstanceID:       "i-web0",
+		SecurityGroupIDs: []string{"sg-1"},
+		NetworkInterfaces: []entities.NetworkInterface{
+			{ID: "eni-b", DeviceIndex: 1, SecurityGroupIDs: []string{"sg-db"}},
+		},
+	}
+	awsConfig := entities.InstanceConfig{
+		InstanceID:       "i-web0",
+		SecurityGroupIDs: []string{"sg-1"},
+		NetworkInterfaces: []entities.NetworkInterface{
+			{ID: "eni-b", DeviceIndex: 1, SecurityGroupIDs: []string{"sg-db", "sg-mgmt"}},
+			// Not in the state, so not compared
+			{ID: "eni-c", DeviceIndex: 2, SecurityGroupIDs: []string{"sg-x"}},
+		},
+	}
+
+	changes := diffInstances(tfConfig, awsConfig)
+	assert.Equal(t, []AttributeChange{{
+		Path:  `network_interface["eni-b"].security_groups`,
+		Kind:  entities.ChangeModified,
+		From:  []string{"sg-db"},
+		To:    []string{"sg-db", "sg-mgmt"},
+		Added: []string{"sg-mgmt"},
+	}}, changes)
+	assert.Equal(t, entities.SeverityCritical, DefaultSeverityPolicy().classify(changes[0].Path))
+}
+
+func TestDiffInstances_Tags(t *testing.T) {
+	tfConfig := entities.InstanceConfig{
+		InstanceID: "i-web0",
+		Tags:       map[string]string{"Name": "web-a", "Team": "platform", "CostCenter": "42"},
+	}
+	awsConfig := entities.InstanceConfig{
+		InstanceID: "i-web0",
+		Tags:       map[string]string{"Name": "web-b", "Owner": "ops", "Team": "platform", "aws:autoscaling:groupName": "web-asg", "kubernetes.io/cluster": "owned"},
+	}
+
+	changes := diffInstances(tfConfig, awsConfig)
+	assert.Equal(t, []AttributeChange{
+		{Path: "tags.CostCenter", Kind: entities.ChangeRemoved, From: "42"},
+		{Path: "tags.Name", Kind: entities.ChangeModified, From: "web-a", To: "web-b"},
+		{Path: "tags.Owner", Kind: entities.ChangeAdded, To: "ops"},
+		{Path: "tags.aws:autoscaling:groupName", Kind: entities.ChangeAdded, To: "web-asg"},
+		{Path: `tags["kubernetes.io/cluster"]`, Kind: entities.ChangeAdded, To: "owned"},
+	}, changes)
+
+	changes = dropIgnoredTags(changes, newGlobMatcher([]string{"aws:*", "kubernetes.io/*"}))
+	assert.Equal(t, "Drift detected for instance i-web0 (aws_instance.web):\n"+
+		"  - tags.CostCenter: AWS=(not set), Terraform=42\n"+
+		"  - tags.Name: AWS=web-b, Terraform=web-a\n"+
+		"  - tags.Owner: AWS=ops, Terraform=(not set)\n",
+		formatDrift("i-web0", "aws_instance.web", changes))
+
+	// A state without tags reports each live tag, so ignore patterns and rules still apply per key
+	tfConfig.Tags = nil
+	assert.Len(t, dropIgnoredTags(diffInstances(tfConfig, awsConfig), newGlobMatcher(DefaultIgnoredTags)), 4)
+}
@@ -0,0 +1,136 @@
+package usecases
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/cstudio7/drift-detector/internal/interfaces/terraform"
+)
+
+// stateConflict is an instance ID claimed by resources in more than one state.
+type stateConflict struct {
+	instanceID string
+	claims     []terraform.InstanceResource
+}
+
+// parseStates parses every state concurrently and merges them, in the given order, into one
+// desired-state inventory. When states claim the same instance ID the first claim is kept and the
+// others are returned as conflicts, ordered by instance ID. Any state that fails to parse fails
+// the whole inventory, since comparing against a partial one would report its instances as
+// unmanaged.
+func (d *DriftDetector) parseStates(tfStateFiles []string) (terraform.InstanceConfigSet, []stateConflict, error) {
+	if len(tfStateFiles) == 0 {
+		return terraform.InstanceConfigSet{}, nil, fmt.Errorf("no state files given")
+	}
+
+	type parseResult struct {
+		configs terraform.InstanceConfigSet
+		err     error
+	}
+	results := make([]parseResult, len(tfStateFiles))
+
+	var wg sync.WaitGroup
+	for i, file := range tfStateFiles {
+		wg.Add(1)
+		go func(i int, file string) {
+			defer wg.Done()
+			configs, err := d.tfParser.ParseTFState(file)
+			results[i] = parseResult{configs: configs, err: err}
+		}(i, file)
+	}
+	wg.Wait()
+
+	var errs []error
+	for i, result := range results {
+		if result.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", terraform.RedactLocation(tfStateFiles[i]), result.err))
+		}
+	}
+	if len(errs) > 0 {
+		return terraform.InstanceConfigSet{}, nil, errors.Join(errs...)
+	}
+
+	// A single state needs no merging; label it only if the caller named it
+	if len(tfStateFiles) == 1 && d.stateLabels[tfStateFiles[0]] == "" {
+		return results[0].configs, nil, nil
+	}
+
+	var merged terraform.InstanceConfigSet
+	conflicts := make(map[string]*stateConflict)
+	var order []string
+	for i, result := range results {
+		for id, resource := range result.configs.Instances {
+			resource.Source = d.stateLabel(tfStateFiles[i])
+			result.configs.Instances[id] = resource
+		}
+		for j := range result.configs.States {
+			result.configs.States[j].Source = d.stateLabel(tfStateFiles[i])
+		}
+		for _, id := range merged.Merge(result.configs) {
+			conflict, ok := conflicts[id]
+			if !ok {
+				conflict = &stateConflict{instanceID: id, claims: []terraform.InstanceResource{merged.Instances[id]}}
+				conflicts[id] = conflict
+				order = append(order, id)
+			}
+			conflict.claims = append(conflict.claims, result.configs.Instances[id])
+		}
+	}
+
+	sort.Strings(order)
+	var conflictList []stateConflict
+	for _, id := range order {
+		conflictList = append(conflictList, *conflicts[id])
+	}
+	return merged, conflictList, nil
+}
+
+// stateLabel returns the label given for a state, or its location without credentials.
+func (d *DriftDetector) stateLabel(tfStateFile string) string {
+	if label, ok := d.stateLabels[tfStateFile]; ok && label != "" {
+		return label
+	}
+	return terraform.RedactLocation(tfStateFile)
+}
+
+// staleState is a state with the lineage of another, newer state: an older copy of the same state.
+type staleState struct {
+	stale  terraform.StateVersion
+	newest terraform.StateVersion
+}
+
+// findStaleStates pairs every state with the highest-serial state of its lineage, when that is a
+// different, newer one.
+func findStaleStates(states []terraform.StateVersion) []staleState {
+	newest := make(map[string]terraform.StateVersion)
+	for _, state := range states {
+		if state.Lineage == "" {
+			continue
+		}
+		if current, ok := newest[state.Lineage]; !ok || state.Serial > current.Serial {
+			newest[state.Lineage] = state
+		}
+	}
+	var stale []staleState
+	for _, state := range states {
+		if n, ok := newest[state.Lineage]; ok && state.Serial < n.Serial {
+			stale = append(stale, staleState{stale: state, newest: n})
+		}
+	}
+	return stale
+}
+
+func formatStale(s staleState) string {
+	return fmt.Sprintf("Stale state %s: serial %d of lineage %s, but %s has serial %d\n",
+		s.stale.Source, s.stale.Serial, s.stale.Lineage, s.newest.Source, s.newest.Serial)
+}
+
+func formatConflict(conflict stateConflict) string {
+	msg := fmt.Sprintf("Conflict for instance %s: claimed by %d Terraform resources, comparing against the first\n", conflict.instanceID, len(conflict.claims))
+	for _, claim := range conflict.claims {
+		msg += fmt.Sprintf("  - %s\n", resourceLabel(claim))
+	}
+	return msg
+}
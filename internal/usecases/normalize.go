@@ -0,0 +1,104 @@
+package usecases
+
+import (
+	"strings"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+	"github.com/cstudio7/drift-detector/internal/interfaces/terraform"
+)
+
+// normalizer canonicalises the values of one attribute, so that equivalent representations, such
+// as an IAM instance profile's ARN and its name, compare equal. Values it cannot resolve are
+// returned unchanged.
+type normalizer struct {
+	// instance and set return the attribute's values in an instance config and in the aggregated
+	// lists of a legacy state. Both copy any list before returning pointers into it, since configs
+	// share their lists with the parsed state.
+	instance  func(*entities.InstanceConfig) []*string
+	set       func(*terraform.InstanceConfigSet) []*string
+	normalize func(value string, lookups entities.Lookups) string
+}
+
+// normalizers holds the normalizer of every attribute with more than one representation, keyed by
+// attribute. Each converts values to the form AWS returns, except iam_instance_profile: AWS
+// returns the ARN while Terraform stores the name.
+var normalizers = map[string]normalizer{
+	"iam_instance_profile": {
+		instance:  func(c *entities.InstanceConfig) []*string { return []*string{&c.IAMInstanceProfile} },
+		set:       func(c *terraform.InstanceConfigSet) []*string { return copyValues(&c.IAMInstanceProfiles) },
+		normalize: instanceProfileName,
+	},
+	"availability_zone": {
+		instance:  func(c *entities.InstanceConfig) []*string { return []*string{&c.AvailabilityZone} },
+		set:       func(c *terraform.InstanceConfigSet) []*string { return copyValues(&c.AvailabilityZones) },
+		normalize: zoneName,
+	},
+	"vpc_security_group_ids": {
+		instance:  func(c *entities.InstanceConfig) []*string { return copyValues(&c.SecurityGroupIDs) },
+		set:       func(c *terraform.InstanceConfigSet) []*string { return copyValues(&c.SecurityGroupIDs) },
+		normalize: securityGroupID,
+	},
+}
+
+// normalizeConfig returns config with every registered attribute normalised.
+func normalizeConfig(config entities.InstanceConfig, lookups entities.Lookups) entities.InstanceConfig {
+	for _, n := range normalizers {
+		for _, value := range n.instance(&config) {
+			*value = n.normalize(*value, lookups)
+		}
+	}
+	return config
+}
+
+// normalizeConfigSet returns the aggregated lists of a legacy state with every registered
+// attribute normalised.
+func normalizeConfigSet(configs terraform.InstanceConfigSet, lookups entities.Lookups) terraform.InstanceConfigSet {
+	for _, n := range normalizers {
+		for _, value := range n.set(&configs) {
+			*value = n.normalize(*value, lookups)
+		}
+	}
+	return configs
+}
+
+// copyValues replaces *values with a copy and returns pointers to its elements.
+func copyValues(values *[]string) []*string {
+	if len(*values) == 0 {
+		return nil
+	}
+	*values = append([]string(nil), *values...)
+	pointers := make([]*string, len(*values))
+	for i := range *values {
+		pointers[i] = &(*values)[i]
+	}
+	return pointers
+}
+
+// instanceProfileName returns the name of an instance profile ARN, e.g. web-server-role for
+// arn:aws:iam::123456789012:instance-profile/app/web-server-role.
+func instanceProfileName(value string, _ entities.Lookups) string {
+	if !strings.HasPrefix(value, "arn:") || !strings.Contains(value, ":instance-profile/") {
+		return value
+	}
+	return value[strings.LastIndex(value, "/")+1:]
+}
+
+// zoneName returns the name of an availability zone ID, e.g. us-east-1a for use1-az1.
+func zoneName(value string, lookups entities.Lookups) string {
+	if name, ok := lookups.ZoneNames[value]; ok {
+		return name
+	}
+	return value
+}
+
+// securityGroupID returns the ID of a security group name, as held in security_groups by
+// older states and default-VPC instances.
+func securityGroupID(value string, lookups entities.Lookups) string {
+	if strings.HasPrefix(value, "sg-") {
+		return value
+	}
+	if id, ok := lookups.SecurityGroupIDs[value]; ok {
+		return id
+	}
+	return value
+}
@@ -0,0 +1,89 @@
+package usecases
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+	"github.com/cstudio7/drift-detector/internal/interfaces/terraform"
+)
+
+// mockLookupsAWSClient is a mockAWSClient that resolves security group names and zone IDs.
+type mockLookupsAWSClient struct {
+	mockAWSClient
+	lookups entities.Lookups
+}
+
+func (m *mockLookupsAWSClient) Lookups() (entities.Lookups, error) { return m.lookups, nil }
+
+var testLookups = entities.Lookups{
+	SecurityGroupIDs: map[string]string{"web": "sg-1", "ssh": "sg-2"},
+	ZoneNames:        map[string]string{"use1-az1": "us-east-1b"},
+}
+
+func TestNormalizeConfig(t *testing.T) {
+	groups := []string{"web", "sg-2", "unknown"}
+	config := normalizeConfig(entities.InstanceConfig{
+		IAMInstanceProfile: "arn:aws:iam::123456789012:instance-profile/app/web-server-role",
+		AvailabilityZone:   "use1-az1",
+		SecurityGroupIDs:   groups,
+	}, testLookups)
+
+	assert.Equal(t, "web-server-role", config.IAMInstanceProfile)
+	assert.Equal(t, "us-east-1b", config.AvailabilityZone)
+	assert.Equal(t, []string{"sg-1", "sg-2", "unknown"}, config.SecurityGroupIDs)
+	// The state's own list is left alone
+	assert.Equal(t, []string{"web", "sg-2", "unknown"}, groups)
+
+	// Values already in canonical form, or unresolvable, are unchanged
+	assert.Equal(t, "web-server-role", instanceProfileName("web-server-role", entities.Lookups{}))
+	assert.Equal(t, "arn:aws:iam::123456789012:role/web", instanceProfileName("arn:aws:iam::123456789012:role/web", entities.Lookups{}))
+	assert.Equal(t, "us-east-1a", zoneName("us-east-1a", testLookups))
+	assert.Equal(t, "default", securityGroupID("default", entities.Lookups{}))
+}
+
+func TestNormalizeConfigSet(t *testing.T) {
+	configs := normalizeConfigSet(terraform.InstanceConfigSet{
+		IAMInstanceProfiles: []string{"web-server-role"},
+		AvailabilityZones:   []string{"use1-az1"},
+		SecurityGroupIDs:    []string{"web", "ssh"},
+	}, testLookups)
+
+	assert.Equal(t, []string{"web-server-role"}, configs.IAMInstanceProfiles)
+	assert.Equal(t, []string{"us-east-1b"}, configs.AvailabilityZones)
+	assert.Equal(t, []string{"sg-1", "sg-2"}, configs.SecurityGroupIDs)
+}
+
+func TestDetectDrift_NormalizesEquivalentValues(t *testing.T) {
+	mockAWS := &mockLookupsAWSClient{
+		mockAWSClient: mockAWSClient{
+			fetchConfigs: func() ([]entities.InstanceConfig, error) {
+				return []entities.InstanceConfig{{
+					InstanceID:         "i-web",
+					InstanceType:       "t2.micro",
+					IAMInstanceProfile: "arn:aws:iam::123456789012:instance-profile/web-server-role",
+					AvailabilityZone:   "us-east-1b",
+					SecurityGroupIDs:   []string{"sg-2", "sg-1"},
+				}}, nil
+			},
+		},
+		lookups: testLookups,
+	}
+	mockTF := &mockTFStateParser{
+		parseFunc: func(tfStateFile string) (terraform.InstanceConfigSet, error) {
+			return stateWith(terraform.InstanceResource{Type: "aws_instance", Name: "web", Config: entities.InstanceConfig{
+				InstanceID:         "i-web",
+				InstanceType:       "t2.micro",
+				IAMInstanceProfile: "web-server-role",
+				AvailabilityZone:   "use1-az1",
+				SecurityGroupIDs:   []string{"web", "ssh"},
+			}}), nil
+		},
+	}
+	detector := NewDriftDetector(mockAWS, &mockLogger{}, WithTFParser(mockTF))
+
+	result, err := detector.DetectDrift("mock.tfstate")
+	assert.NoError(t, err)
+	assert.Equal(t, []entities.DriftReport{{InstanceID: "i-web", Address: "aws_instance.web"}}, result.Reports)
+}
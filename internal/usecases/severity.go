@@ -0,0 +1,164 @@
+package usecases
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+)
+
+// SeverityRule classifies the drifted fields matching Attribute, a glob over attribute paths as
+// reported, e.g. vpc_security_group_ids, tags.Name or root_block_device[0].*.
+type SeverityRule struct {
+	Attribute string            `yaml:"attribute"`
+	Severity  entities.Severity `yaml:"severity"`
+
+	attribute globMatcher
+}
+
+// SeverityPolicy classifies drift. Rules are tried in order and the first match wins; fields no
+// rule matches are Default.
+type SeverityPolicy struct {
+	Rules     []SeverityRule    `yaml:"rules"`
+	Default   entities.Severity `yaml:"default"`
+	Unmanaged entities.Severity `yaml:"unmanaged"`
+	Missing   entities.Severity `yaml:"missing"`
+}
+
+// severityWeights is what one change of each severity adds to a run's risk score.
+var severityWeights = map[entities.Severity]int{
+	entities.SeverityLow:      1,
+	entities.SeverityMedium:   3,
+	entities.SeverityHigh:     7,
+	entities.SeverityCritical: 15,
+}
+
+// DefaultSeverityPolicy rates changes to network exposure and access as critical or high, and tags
+// other than Environment as low.
+func DefaultSeverityPolicy() *SeverityPolicy {
+	policy := &SeverityPolicy{
+		Rules: []SeverityRule{
+			{Attribute: "vpc_security_group_ids", Severity: entities.SeverityCritical},
+			{Attribute: "network_interface[*].security_groups", Severity: entities.SeverityCritical},
+			{Attribute: "iam_instance_profile", Severity: entities.SeverityCritical},
+			{Attribute: "subnet_id", Severity: entities.SeverityHigh},
+			{Attribute: "key_name", Severity: entities.SeverityHigh},
+			{Attribute: "ami", Severity: entities.SeverityHigh},
+			{Attribute: "*.encrypted", Severity: entities.SeverityHigh},
+			{Attribute: "*.kms_key_id", Severity: entities.SeverityHigh},
+			{Attribute: "tags.Environment", Severity: entities.SeverityMedium},
+			{Attribute: "tags.*", Severity: entities.SeverityLow},
+			{Attribute: "tags[*]", Severity: entities.SeverityLow},
+		},
+		Default:   entities.SeverityMedium,
+		Unmanaged: entities.SeverityHigh,
+		Missing:   entities.SeverityHigh,
+	}
+	// The rules above are valid
+	_ = policy.compile()
+	return policy
+}
+
+// LoadSeverityPolicy reads a severity policy in YAML or JSON. Its rules are tried before those of
+// DefaultSeverityPolicy, and the severities it leaves out keep their defaults.
+func LoadSeverityPolicy(path string) (*SeverityPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", entities.ErrInvalidArguments, err)
+	}
+	var policy SeverityPolicy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("%w: severity policy %s: %v", entities.ErrInvalidArguments, path, err)
+	}
+
+	defaults := DefaultSeverityPolicy()
+	policy.Rules = append(policy.Rules, defaults.Rules...)
+	for _, severity := range []struct {
+		value    *entities.Severity
+		fallback entities.Severity
+	}{
+		{&policy.Default, defaults.Default},
+		{&policy.Unmanaged, defaults.Unmanaged},
+		{&policy.Missing, defaults.Missing},
+	} {
+		if *severity.value == "" {
+			*severity.value = severity.fallback
+		}
+	}
+	if err := policy.compile(); err != nil {
+		return nil, fmt.Errorf("%w: severity policy %s: %v", entities.ErrInvalidArguments, path, err)
+	}
+	return &policy, nil
+}
+
+// compile validates the policy's severities and builds the rules' matchers.
+func (p *SeverityPolicy) compile() error {
+	for _, severity := range []*entities.Severity{&p.Default, &p.Unmanaged, &p.Missing} {
+		parsed, err := entities.ParseSeverity(string(*severity))
+		if err != nil {
+			return err
+		}
+		*severity = parsed
+	}
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.Attribute == "" {
+			return fmt.Errorf("rule %d: attribute is required", i+1)
+		}
+		severity, err := entities.ParseSeverity(string(rule.Severity))
+		if err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		rule.Severity = severity
+		rule.attribute = newGlobMatcher([]string{rule.Attribute})
+	}
+	return nil
+}
+
+// classify returns the severity of a drifted field.
+func (p *SeverityPolicy) classify(field string) entities.Severity {
+	for _, rule := range p.Rules {
+		if rule.attribute.matches(field) {
+			return rule.Severity
+		}
+	}
+	return p.Default
+}
+
+// classifyChanges sets the severity of every change and returns the highest.
+func (p *SeverityPolicy) classifyChanges(changes map[string]entities.Change) entities.Severity {
+	var highest entities.Severity
+	for field, change := range changes {
+		change.Severity = p.classify(field)
+		changes[field] = change
+		highest = maxSeverity(highest, change.Severity)
+	}
+	return highest
+}
+
+func maxSeverity(a, b entities.Severity) entities.Severity {
+	if b.Rank() > a.Rank() {
+		return b
+	}
+	return a
+}
+
+// scoreRun sets the run's risk score and highest severity from its reports. Suppressed drift is
+// not scored.
+func scoreRun(run *entities.DriftResult) {
+	for _, report := range run.Reports {
+		if !report.HasDrift {
+			continue
+		}
+		run.MaxSeverity = maxSeverity(run.MaxSeverity, report.Severity)
+		if len(report.Changes) == 0 {
+			run.RiskScore += severityWeights[report.Severity]
+			continue
+		}
+		for _, change := range report.Changes {
+			run.RiskScore += severityWeights[change.Severity]
+		}
+	}
+}
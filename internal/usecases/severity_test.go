@@ -0,0 +1,80 @@
+package usecases
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+)
+
+func TestSeverityPolicy_Classify(t *testing.T) {
+	policy := DefaultSeverityPolicy()
+
+	assert.Equal(t, entities.SeverityCritical, policy.classify("vpc_security_group_ids"))
+	assert.Equal(t, entities.SeverityHigh, policy.classify(`ebs_block_device["/dev/sdf"].encrypted`))
+	assert.Equal(t, entities.SeverityHigh, policy.classify("root_block_device[0].kms_key_id"))
+	assert.Equal(t, entities.SeverityMedium, policy.classify("tags.Environment"))
+	assert.Equal(t, entities.SeverityLow, policy.classify("tags.Name"))
+	assert.Equal(t, entities.SeverityLow, policy.classify(`tags["kubernetes.io/cluster"]`))
+	assert.Equal(t, entities.SeverityMedium, policy.classify("instance_type"))
+
+	changes := map[string]entities.Change{"tags.Name": {}, "subnet_id": {}}
+	assert.Equal(t, entities.SeverityHigh, policy.classifyChanges(changes))
+	assert.Equal(t, entities.SeverityLow, changes["tags.Name"].Severity)
+}
+
+func TestLoadSeverityPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "severity.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+rules:
+  - attribute: tags.Owner
+    severity: high
+  - attribute: instance_type
+    severity: Low
+unmanaged: critical
+`), 0o644))
+
+	policy, err := LoadSeverityPolicy(path)
+	assert.NoError(t, err)
+	assert.Equal(t, entities.SeverityHigh, policy.classify("tags.Owner"))
+	assert.Equal(t, entities.SeverityLow, policy.classify("instance_type"))
+	// The defaults still apply to everything else
+	assert.Equal(t, entities.SeverityCritical, policy.classify("vpc_security_group_ids"))
+	assert.Equal(t, entities.SeverityLow, policy.classify("tags.Name"))
+	assert.Equal(t, entities.SeverityCritical, policy.Unmanaged)
+	assert.Equal(t, entities.SeverityHigh, policy.Missing)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"rules": [{"attribute": "ami", "severity": "urgent"}]}`), 0o644))
+	_, err = LoadSeverityPolicy(path)
+	assert.ErrorIs(t, err, entities.ErrInvalidArguments)
+}
+
+func TestScoreRun(t *testing.T) {
+	run := &entities.DriftResult{Reports: []entities.DriftReport{
+		{InstanceID: "i-1", HasDrift: true, Severity: entities.SeverityCritical, Changes: map[string]entities.Change{
+			"vpc_security_group_ids": {Severity: entities.SeverityCritical},
+			"tags.Name":              {Severity: entities.SeverityLow},
+		}},
+		{InstanceID: "i-2", Kind: entities.DriftUnmanaged, HasDrift: true, Severity: entities.SeverityHigh},
+		{InstanceID: "i-3", Kind: entities.DriftMissing, Severity: entities.SeverityHigh, Suppressed: map[string]string{"missing": "Decommissioned"}},
+		{InstanceID: "i-4"},
+	}}
+
+	scoreRun(run)
+
+	assert.Equal(t, 15+1+7, run.RiskScore)
+	assert.Equal(t, entities.SeverityCritical, run.MaxSeverity)
+}
+
+func TestParseSeverity(t *testing.T) {
+	severity, err := entities.ParseSeverity("HIGH")
+	assert.NoError(t, err)
+	assert.Equal(t, entities.SeverityHigh, severity)
+	assert.Greater(t, entities.SeverityCritical.Rank(), severity.Rank())
+
+	_, err = entities.ParseSeverity("severe")
+	assert.ErrorIs(t, err, entities.ErrInvalidArguments)
+}
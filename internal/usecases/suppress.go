@@ -0,0 +1,152 @@
+package usecases
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+	"github.com/cstudio7/drift-detector/internal/interfaces/logger"
+)
+
+// SuppressionRule accepts known drift. A rule applies to the instances matching all of its
+// selectors, and suppresses the drifted fields matching one of its attributes, or every field when
+// it lists none. Address and attributes are glob patterns, e.g. module.asg.* or tags.aws:*.
+type SuppressionRule struct {
+	Address    string `yaml:"address"`
+	InstanceID string `yaml:"instance_id"`
+	// Tags selects instances whose live tags, or for missing instances desired tags, have these values.
+	Tags map[string]string `yaml:"tags"`
+	// Attributes are drifted attribute paths as reported, e.g. instance_type or
+	// root_block_device[0].volume_size.
+	Attributes []string `yaml:"attributes"`
+	// Expires is the last day the rule applies, as 2006-01-02, or an RFC 3339 time.
+	Expires       string `yaml:"expires"`
+	Justification string `yaml:"justification"`
+
+	expires    time.Time
+	address    globMatcher
+	attributes globMatcher
+}
+
+// Suppressions is the set of rules read from a suppression file.
+type Suppressions struct {
+	Rules []SuppressionRule `yaml:"rules"`
+}
+
+// LoadSuppressions reads a suppression file, in YAML or JSON, and validates its rules. Every rule
+// needs a justification and at least one selector or attribute.
+func LoadSuppressions(path string) (*Suppressions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", entities.ErrInvalidSuppressions, err)
+	}
+	var suppressions Suppressions
+	if err := yaml.Unmarshal(data, &suppressions); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", entities.ErrInvalidSuppressions, path, err)
+	}
+	for i := range suppressions.Rules {
+		if err := suppressions.Rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("%w: %s: rule %d: %v", entities.ErrInvalidSuppressions, path, i+1, err)
+		}
+	}
+	return &suppressions, nil
+}
+
+func (r *SuppressionRule) compile() error {
+	if r.Justification == "" {
+		return fmt.Errorf("justification is required")
+	}
+	if r.Address == "" && r.InstanceID == "" && len(r.Tags) == 0 && len(r.Attributes) == 0 {
+		return fmt.Errorf("at least one of address, instance_id, tags or attributes is required")
+	}
+	if r.Expires != "" {
+		if day, err := time.Parse(time.DateOnly, r.Expires); err == nil {
+			// A date-only expiry includes the whole day
+			r.expires = day.AddDate(0, 0, 1)
+		} else if r.expires, err = time.Parse(time.RFC3339, r.Expires); err != nil {
+			return fmt.Errorf("expires %q is neither a date nor an RFC 3339 time", r.Expires)
+		}
+	}
+	if r.Address != "" {
+		r.address = newGlobMatcher([]string{r.Address})
+	}
+	r.attributes = newGlobMatcher(r.Attributes)
+	return nil
+}
+
+// suppressionTarget is the instance a rule is matched against.
+type suppressionTarget struct {
+	address    string
+	instanceID string
+	tags       map[string]string
+}
+
+func (r SuppressionRule) selects(target suppressionTarget) bool {
+	if r.address != nil && !r.address.matches(target.address) {
+		return false
+	}
+	if r.InstanceID != "" && r.InstanceID != target.instanceID {
+		return false
+	}
+	for key, value := range r.Tags {
+		if actual, ok := target.tags[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// active returns the rules that have not expired by now, warning about the others so that stale
+// rules get reviewed.
+func (s *Suppressions) active(now time.Time, log logger.Logger) []SuppressionRule {
+	if s == nil {
+		return nil
+	}
+	var rules []SuppressionRule
+	for _, rule := range s.Rules {
+		if !rule.expires.IsZero() && !now.Before(rule.expires) {
+			log.Warn("Suppression rule expired, drift it matches is reported", "address", rule.Address, "instance_id", rule.InstanceID, "expires", rule.Expires, "justification", rule.Justification)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// suppressFields removes the changes of target that a rule accepts, and returns the others with
+// the paths of those removed mapped to the justification of the first rule matching each.
+func suppressFields(rules []SuppressionRule, target suppressionTarget, changes []AttributeChange) ([]AttributeChange, map[string]string) {
+	var suppressed map[string]string
+	for _, rule := range rules {
+		if !rule.selects(target) {
+			continue
+		}
+		var kept []AttributeChange
+		for _, change := range changes {
+			if len(rule.Attributes) > 0 && !rule.attributes.matches(change.Path) {
+				kept = append(kept, change)
+				continue
+			}
+			if suppressed == nil {
+				suppressed = make(map[string]string)
+			}
+			suppressed[change.Path] = rule.Justification
+		}
+		changes = kept
+	}
+	return changes, suppressed
+}
+
+// suppressInstance returns the justification of the first rule accepting target as a whole, as
+// an unmanaged or missing instance. Only rules without attributes do.
+func suppressInstance(rules []SuppressionRule, target suppressionTarget) (string, bool) {
+	for _, rule := range rules {
+		if len(rule.Attributes) == 0 && rule.selects(target) {
+			return rule.Justification, true
+		}
+	}
+	return "", false
+}
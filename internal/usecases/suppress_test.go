@@ -0,0 +1,137 @@
+package usecases
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+	"github.com/cstudio7/drift-detector/internal/interfaces/terraform"
+)
+
+func writeSuppressions(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadSuppressions(t *testing.T) {
+	yamlPath := writeSuppressions(t, "suppressions.yaml", `
+rules:
+  - address: aws_instance.batch*
+    attributes: [instance_type]
+    expires: 2026-12-31
+    justification: Resized by ops
+  - tags: {Team: data}
+    attributes: ["tags[\"kubernetes.io/*"]
+    justification: Managed by the cluster
+`)
+	suppressions, err := LoadSuppressions(yamlPath)
+	assert.NoError(t, err)
+	assert.Len(t, suppressions.Rules, 2)
+	assert.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), suppressions.Rules[0].expires)
+	assert.Equal(t, map[string]string{"Team": "data"}, suppressions.Rules[1].Tags)
+
+	jsonPath := writeSuppressions(t, "suppressions.json", `{"rules": [{"instance_id": "i-1", "expires": "2026-06-01T12:00:00Z", "justification": "Decommissioning"}]}`)
+	suppressions, err = LoadSuppressions(jsonPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "i-1", suppressions.Rules[0].InstanceID)
+	assert.Equal(t, time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC), suppressions.Rules[0].expires)
+
+	for name, content := range map[string]string{
+		"NoJustification": `rules: [{instance_id: i-1}]`,
+		"NoSelector":      `rules: [{justification: everything}]`,
+		"BadExpiry":       `rules: [{instance_id: i-1, expires: next week, justification: soon}]`,
+		"NotYAML":         `rules: [`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadSuppressions(writeSuppressions(t, "suppressions.yaml", content))
+			assert.ErrorIs(t, err, entities.ErrInvalidSuppressions)
+		})
+	}
+
+	_, err = LoadSuppressions(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorIs(t, err, entities.ErrInvalidSuppressions)
+}
+
+func TestSuppressFields(t *testing.T) {
+	suppressions, err := LoadSuppressions(writeSuppressions(t, "suppressions.yaml", `
+rules:
+  - address: aws_instance.web*
+    tags: {Team: platform}
+    attributes: [instance_type, "tags.Owner*"]
+    justification: Accepted
+`))
+	assert.NoError(t, err)
+	rules := suppressions.active(time.Now(), &mockLogger{})
+
+	changes := []AttributeChange{
+		{Path: "instance_type", Kind: entities.ChangeModified, From: "t3.micro", To: "t3.large"},
+		{Path: "subnet_id", Kind: entities.ChangeModified, From: "subnet-a", To: "subnet-b"},
+		{Path: "tags.Owner", Kind: entities.ChangeAdded, To: "ops"},
+	}
+	target := suppressionTarget{address: "aws_instance.web[0]", instanceID: "i-web0", tags: map[string]string{"Team": "platform"}}
+	kept, suppressed := suppressFields(rules, target, changes)
+	assert.Equal(t, map[string]string{"instance_type": "Accepted", "tags.Owner": "Accepted"}, suppressed)
+	assert.Equal(t, []AttributeChange{{Path: "subnet_id", Kind: entities.ChangeModified, From: "subnet-a", To: "subnet-b"}}, kept)
+
+	// Every selector has to match
+	target.tags = map[string]string{"Team": "data"}
+	kept, suppressed = suppressFields(rules, target, changes[:1])
+	assert.Empty(t, suppressed)
+	assert.Len(t, kept, 1)
+
+	// A rule with attributes does not accept an instance as a whole
+	_, ok := suppressInstance(rules, suppressionTarget{address: "aws_instance.web[0]", tags: map[string]string{"Team": "platform"}})
+	assert.False(t, ok)
+}
+
+func TestDetectDrift_Suppressions(t *testing.T) {
+	mockAWS := &mockAWSClient{
+		fetchConfigs: func() ([]entities.InstanceConfig, error) {
+			return []entities.InstanceConfig{
+				{InstanceID: "i-web", InstanceType: "t3.large", SubnetID: "subnet-b"},
+				{InstanceID: "i-stray", InstanceType: "t2.micro", Tags: map[string]string{"aws:autoscaling:groupName": "batch"}},
+				{InstanceID: "i-other", InstanceType: "t2.micro"},
+			}, nil
+		},
+	}
+	mockTF := &mockTFStateParser{
+		parseFunc: func(tfStateFile string) (terraform.InstanceConfigSet, error) {
+			return stateWith(
+				terraform.InstanceResource{Type: "aws_instance", Name: "web", Config: entities.InstanceConfig{InstanceID: "i-web", InstanceType: "t3.micro", SubnetID: "subnet-a"}},
+			), nil
+		},
+	}
+	suppressions := &Suppressions{Rules: []SuppressionRule{
+		{Address: "aws_instance.web", Attributes: []string{"instance_type"}, Justification: "Resized by ops"},
+		{Tags: map[string]string{"aws:autoscaling:groupName": "batch"}, Justification: "Launched by the batch group"},
+		{InstanceID: "i-web", Attributes: []string{"subnet_id"}, Expires: "2020-01-01", Justification: "Moved during the outage"},
+	}}
+	for i := range suppressions.Rules {
+		assert.NoError(t, suppressions.Rules[i].compile())
+	}
+	detector := NewDriftDetector(mockAWS, &mockLogger{}, WithTFParser(mockTF), WithSuppressions(suppressions))
+
+	result, err := detector.DetectDrift("mock.tfstate")
+	assert.NoError(t, err)
+	assert.Equal(t, entities.DriftCounts{Compared: 1, Modified: 1, Unmanaged: 1, Suppressed: 2}, result.Counts)
+	assert.Equal(t, []entities.DriftReport{
+		{InstanceID: "i-other", Kind: entities.DriftUnmanaged, HasDrift: true, Severity: entities.SeverityHigh},
+		{InstanceID: "i-stray", Kind: entities.DriftUnmanaged, Suppressed: map[string]string{"unmanaged": "Launched by the batch group"}},
+		{
+			InstanceID: "i-web",
+			Address:    "aws_instance.web",
+			Kind:       entities.DriftModified,
+			HasDrift:   true,
+			Severity:   entities.SeverityHigh,
+			// The expired rule no longer applies
+			Changes:    map[string]entities.Change{"subnet_id": {Expected: "subnet-a", Actual: "subnet-b", Kind: entities.ChangeModified, Severity: entities.SeverityHigh}},
+			Suppressed: map[string]string{"instance_type": "Resized by ops"},
+		},
+	}, result.Reports)
+}
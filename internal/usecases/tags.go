@@ -0,0 +1,64 @@
+package usecases
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultIgnoredTags are the tag keys AWS manages itself, e.g. aws:autoscaling:groupName or
+// aws:cloudformation:stack-name. No Terraform configuration can set them.
+var DefaultIgnoredTags = []string{"aws:*"}
+
+// globMatcher matches values such as tag keys and resource addresses against glob patterns, where
+// * matches any run of characters, including the / and : tag keys use as separators.
+type globMatcher []*regexp.Regexp
+
+func newGlobMatcher(patterns []string) globMatcher {
+	var matcher globMatcher
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+		matcher = append(matcher, regexp.MustCompile(expr))
+	}
+	return matcher
+}
+
+func (m globMatcher) matches(key string) bool {
+	for _, re := range m {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// dropIgnoredTags removes the changes to tags whose key matches an ignore pattern.
+func dropIgnoredTags(changes []AttributeChange, ignored globMatcher) []AttributeChange {
+	if len(ignored) == 0 {
+		return changes
+	}
+	var kept []AttributeChange
+	for _, change := range changes {
+		if key, ok := tagKey(change.Path); ok && ignored.matches(key) {
+			continue
+		}
+		kept = append(kept, change)
+	}
+	return kept
+}
+
+// tagKey returns the tag key of a path such as tags.Name or tags["kubernetes.io/cluster"].
+func tagKey(path string) (string, bool) {
+	if key, ok := strings.CutPrefix(path, "tags."); ok {
+		return key, true
+	}
+	if quoted, ok := strings.CutPrefix(path, "tags["); ok && strings.HasSuffix(quoted, "]") {
+		key, err := strconv.Unquote(strings.TrimSuffix(quoted, "]"))
+		return key, err == nil
+	}
+	return "", false
+}
@@ -0,0 +1,41 @@
+package usecases
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cstudio7/drift-detector/internal/domain/entities"
+)
+
+func TestGlobMatcher(t *testing.T) {
+	matcher := newGlobMatcher([]string{"aws:*", " kubernetes.io/cluster/* ", "", "Owner"})
+
+	assert.True(t, matcher.matches("aws:cloudformation:stack-name"))
+	assert.True(t, matcher.matches("kubernetes.io/cluster/prod"))
+	assert.True(t, matcher.matches("Owner"))
+	assert.False(t, matcher.matches("Owners"))
+	assert.False(t, matcher.matches("Name"))
+	// Patterns are globs, so regexp metacharacters match literally
+	assert.False(t, newGlobMatcher([]string{"a.c"}).matches("abc"))
+
+	// With no patterns every tag is compared
+	changes := []AttributeChange{{Path: "tags.aws:autoscaling:groupName", Kind: entities.ChangeAdded, To: "web-asg"}}
+	assert.Len(t, dropIgnoredTags(changes, newGlobMatcher(nil)), 1)
+}
+
+func TestTagKey(t *testing.T) {
+	for path, want := range map[string]string{
+		"tags.Name":                      "Name",
+		"tags.aws:autoscaling:groupName": "aws:autoscaling:groupName",
+		`tags["kubernetes.io/cluster"]`:  "kubernetes.io/cluster",
+	} {
+		key, ok := tagKey(path)
+		assert.True(t, ok, path)
+		assert.Equal(t, want, key)
+	}
+	for _, path := range []string{"instance_type", "tags_all.Name", `tags["unterminated]`} {
+		_, ok := tagKey(path)
+		assert.False(t, ok, path)
+	}
+}
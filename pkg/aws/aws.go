@@ -19,3 +19,18 @@ func String(s string) *string {
 func Int32(i int32) *int32 {
 	return aws.Int32(i)
 }
+
+// Bool creates a pointer to a bool.
+func Bool(b bool) *bool {
+	return aws.Bool(b)
+}
+
+// ToInt32 is an alias for aws.ToInt32 from the AWS SDK.
+func ToInt32(i *int32) int32 {
+	return aws.ToInt32(i)
+}
+
+// ToBool is an alias for aws.ToBool from the AWS SDK.
+func ToBool(b *bool) bool {
+	return aws.ToBool(b)
+}
@@ -10,6 +10,9 @@ import (
 // EC2Client is an alias for ec2.Client from the AWS SDK.
 type EC2Client = ec2.Client
 
+// EC2Options is an alias for ec2.Options.
+type EC2Options = ec2.Options
+
 // DescribeInstancesInput is an alias for ec2.DescribeInstancesInput.
 type DescribeInstancesInput = ec2.DescribeInstancesInput
 
@@ -81,3 +84,30 @@ type Filter = ec2types.Filter
 
 // InstanceState is an alias for ec2types.InstanceState.
 type InstanceState = ec2types.InstanceState
+
+// DescribeVolumesInput is an alias for ec2.DescribeVolumesInput.
+type DescribeVolumesInput = ec2.DescribeVolumesInput
+
+// DescribeVolumesOutput is an alias for ec2.DescribeVolumesOutput.
+type DescribeVolumesOutput = ec2.DescribeVolumesOutput
+
+// Volume is an alias for ec2types.Volume.
+type Volume = ec2types.Volume
+
+// DescribeSecurityGroupsInput is an alias for ec2.DescribeSecurityGroupsInput.
+type DescribeSecurityGroupsInput = ec2.DescribeSecurityGroupsInput
+
+// DescribeSecurityGroupsOutput is an alias for ec2.DescribeSecurityGroupsOutput.
+type DescribeSecurityGroupsOutput = ec2.DescribeSecurityGroupsOutput
+
+// SecurityGroup is an alias for ec2types.SecurityGroup.
+type SecurityGroup = ec2types.SecurityGroup
+
+// DescribeAvailabilityZonesInput is an alias for ec2.DescribeAvailabilityZonesInput.
+type DescribeAvailabilityZonesInput = ec2.DescribeAvailabilityZonesInput
+
+// DescribeAvailabilityZonesOutput is an alias for ec2.DescribeAvailabilityZonesOutput.
+type DescribeAvailabilityZonesOutput = ec2.DescribeAvailabilityZonesOutput
+
+// AvailabilityZone is an alias for ec2types.AvailabilityZone.
+type AvailabilityZone = ec2types.AvailabilityZone
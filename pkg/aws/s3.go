@@ -0,0 +1,36 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3Client is an alias for s3.Client from the AWS SDK.
+type S3Client = s3.Client
+
+// S3Options is an alias for s3.Options.
+type S3Options = s3.Options
+
+// GetObjectInput is an alias for s3.GetObjectInput.
+type GetObjectInput = s3.GetObjectInput
+
+// GetObjectOutput is an alias for s3.GetObjectOutput.
+type GetObjectOutput = s3.GetObjectOutput
+
+// InitializeS3 sets up and returns a new S3 client. A non-empty endpoint overrides the default
+// S3 endpoint and switches to path-style addressing, for S3-compatible stand-ins such as MinIO.
+func InitializeS3(ctx context.Context, endpoint string) (*s3.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	}), nil
+}